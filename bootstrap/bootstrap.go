@@ -1,16 +1,20 @@
 package bootstrap
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/skirrund/gcloud/plugins/otel"
 	"github.com/skirrund/gcloud/server"
+	"github.com/skirrund/gcloud/server/health"
 	"github.com/skirrund/gcloud/utils/idworker"
 	"github.com/skirrund/gcloud/utils/worker"
 
@@ -23,6 +27,7 @@ import (
 	"github.com/skirrund/gcloud/mq"
 
 	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/bootstrap/lifecycle"
 )
 
 type Options struct {
@@ -41,6 +46,7 @@ type Application struct {
 	Redis         *redis.RedisClient
 	IdWorker      *idworker.Worker
 	ConfigCenter  config.IConfig
+	Lifecycle     *lifecycle.Manager
 }
 
 type BootstrapOptions struct {
@@ -72,23 +78,55 @@ func StartBase(reader io.Reader, fileType string) *Application {
 
 		log.Println("[Bootstrap]init BootstrapOptions properties:[Profile=" + bo.Profile + "]" + ",[ServerName=" + bo.ServerName + "],[Bind=" + bo.ServerAddress + "]" + ",[LoggerDir=" + bo.LoggerDir + "]")
 	}
-	so := server.Options{
-		ServerName:         bo.ServerName,
-		Address:            bo.ServerAddress,
-		Concurrency:        bo.MaxServerConcurrency,
-		MaxRequestBodySize: bo.MaxRequestBodySize,
-		H2C:                bo.H2C,
-	}
-	MthApplication.ServerOptions = so
+	MthApplication.ServerOptions = newServerOptions(bo)
 	return MthApplication
 }
 
+func newServerOptions(bo BootstrapOptions) server.Options {
+	cfg := env.GetInstance()
+	bodySize := bo.MaxRequestBodySize
+	if bodySize == 0 {
+		bodySize = cfg.GetInt(env.SERVER_MAX_REQUEST_BODY_SIZE_KEY)
+	}
+	return server.Options{
+		ServerName:           bo.ServerName,
+		Address:              bo.ServerAddress,
+		Concurrency:          bo.MaxServerConcurrency,
+		MaxRequestBodySize:   bodySize,
+		H2C:                  bo.H2C,
+		H2:                   bo.H2,
+		ReadTimeout:          cfg.GetDuration(env.SERVER_READ_TIMEOUT_KEY),
+		ReadHeaderTimeout:    cfg.GetDuration(env.SERVER_READ_HEADER_TIMEOUT_KEY),
+		WriteTimeout:         cfg.GetDuration(env.SERVER_WRITE_TIMEOUT_KEY),
+		IdleTimeout:          cfg.GetDuration(env.SERVER_IDLE_TIMEOUT_KEY),
+		ShutdownTimeout:      cfg.GetDuration(env.SERVER_SHUTDOWN_TIMEOUT_KEY),
+		MaxHeaderBytes:       cfg.GetInt(env.SERVER_MAX_HEADER_BYTES_KEY),
+		MaxConcurrentStreams: uint32(cfg.GetUint(env.SERVER_MAX_CONCURRENT_STREAMS_KEY)),
+		TLS: server.TLSOptions{
+			CertFile: cfg.GetString(env.SERVER_TLS_CERT_FILE_KEY),
+			KeyFile:  cfg.GetString(env.SERVER_TLS_KEY_FILE_KEY),
+		},
+	}
+}
+
+// parsePort returns the port of a host:port server address
+func parsePort(address string) (uint64, error) {
+	_, p, err := net.SplitHostPort(address)
+	if err != nil {
+		return 0, fmt.Errorf("server.address error[%s]: %w", address, err)
+	}
+	port, err := strconv.ParseUint(p, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("server.address error[%s]: %w", address, err)
+	}
+	return port, nil
+}
+
 func initBaseOptions(reader io.Reader, fileType string) BootstrapOptions {
 	cfg := env.GetInstance()
 	cfg.SetBaseConfig(reader, fileType)
 	host, _ := os.Hostname()
 	address := cfg.GetString(env.SERVER_ADDRESS_KEY)
-	port := uint64(8080)
 	profile := cfg.GetString(env.SERVER_PROFILE_KEY)
 	sn := cfg.GetString(env.SERVER_SERVERNAME_KEY)
 	ld := cfg.GetString(env.LOGGER_DIR_KEY)
@@ -129,14 +167,9 @@ func initBaseOptions(reader io.Reader, fileType string) BootstrapOptions {
 	if len(flagAddress) == 0 {
 		flagAddress = address
 	}
-	var err error
-	if len(flagAddress) > 0 {
-		port, err = strconv.ParseUint(strings.Split(flagAddress, ":")[1], 10, 64)
-		if err != nil {
-			panic(err)
-		}
-	} else {
-		panic("server.address error[" + flagAddress + "]")
+	port, err := parsePort(flagAddress)
+	if err != nil {
+		panic(err)
 	}
 
 	if len(flagLogdir) == 0 {
@@ -145,18 +178,22 @@ func initBaseOptions(reader io.Reader, fileType string) BootstrapOptions {
 	if flagFasthttpConcurrency == 0 {
 		flagFasthttpConcurrency = fasthttpConcurrency
 	}
-	cfg.Set(env.SERVER_ADDRESS_KEY, flagAddress)
-	cfg.Set(env.SERVER_PORT_KEY, port)
-	cfg.Set(env.SERVER_PROFILE_KEY, flagProfile)
-	cfg.Set(env.SERVER_SERVERNAME_KEY, flagSn)
-	cfg.Set(env.LOGGER_DIR_KEY, flagLogdir)
-	cfg.Set(env.LOGGER_MAXAGE_KEY, flagLogMaxAge)
-	cfg.Set(env.LOGGER_CONSOLE, flagConsoleLog)
-	cfg.Set(env.SERVER_CONFIGFILE_KEY, flagCfgFile)
-	cfg.Set(env.LOGGER_JSON, flagJsonLog)
-	cfg.Set(env.FASTHTTP_concurrency_key, flagFasthttpConcurrency)
-	cfg.Set(env.SERVER_H2C_KEY, flagH2c)
-	cfg.Set(env.SERVER_H2_KEY, flagH2)
+	visited := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		visited[f.Name] = true
+	})
+	setResolved(visited, env.SERVER_ADDRESS_KEY, flagAddress)
+	setResolved(visited, env.SERVER_PORT_KEY, port)
+	setResolved(visited, env.SERVER_PROFILE_KEY, flagProfile)
+	setResolved(visited, env.SERVER_SERVERNAME_KEY, flagSn)
+	setResolved(visited, env.LOGGER_DIR_KEY, flagLogdir)
+	setResolved(visited, env.LOGGER_MAXAGE_KEY, flagLogMaxAge)
+	setResolved(visited, env.LOGGER_CONSOLE, flagConsoleLog)
+	setResolved(visited, env.SERVER_CONFIGFILE_KEY, flagCfgFile)
+	setResolved(visited, env.LOGGER_JSON, flagJsonLog)
+	setResolved(visited, env.FASTHTTP_concurrency_key, flagFasthttpConcurrency)
+	setResolved(visited, env.SERVER_H2C_KEY, flagH2c)
+	setResolved(visited, env.SERVER_H2_KEY, flagH2)
 	cfg.LoadProfileBaseConfig(flagProfile, fileType)
 	return BootstrapOptions{
 		ServerAddress:        flagAddress,
@@ -174,8 +211,22 @@ func initBaseOptions(reader io.Reader, fileType string) BootstrapOptions {
 	}
 }
 
+// setResolved pins a bootstrap value, keeping the origin of values that were not passed as flags
+func setResolved(visited map[string]bool, key string, value any) {
+	cfg := env.GetInstance()
+	source, detail := env.SourceDefault, ""
+	if visited[key] {
+		source = env.SourceFlag
+	} else if entry, ok := cfg.Lookup(key); ok {
+		source, detail = entry.Source, entry.Detail
+	}
+	cfg.SetWithSource(key, value, source, detail)
+}
+
 func (app *Application) Bootstrap(options Options) {
 	app.StartLogger()
+	configureTracing()
+	configureWorkerPools()
 	app.ConfigCenter = options.ConfigCenter
 	app.Registry = options.Registry
 	app.Redis = options.Redis
@@ -184,6 +235,10 @@ func (app *Application) Bootstrap(options Options) {
 		app.IdWorker = worker
 	}
 	app.Mq = options.Mq
+	app.registerComponents()
+	if err := app.Lifecycle.Start(context.Background()); err != nil {
+		logger.Panic("[Bootstrap] start components error:", err.Error())
+	}
 }
 
 func (app *Application) BootstrapAll(options Options) {
@@ -209,8 +264,26 @@ func BootstrapSyncSeparateAsync(funcs ...func()) {
 
 func (app *Application) StartLogger() {
 	ops := app.BootOptions
-	maxAge := env.GetInstance().GetUint64WithDefault(env.LOGGER_MAXAGE_KEY, 7)
-	logger.InitLog(ops.LoggerDir, ops.ServerName, strconv.FormatUint(ops.ServerPort, 10), ops.LoggerConsole, ops.LoggerJson, maxAge)
+	cfg := env.GetInstance()
+	maxAge := cfg.GetUint64WithDefault(env.LOGGER_MAXAGE_KEY, 7)
+	rotation := logger.Rotation{
+		Time:         cfg.GetDurationWithDefault(env.LOGGER_ROTATION_TIME_KEY, time.Hour),
+		MaxSize:      cfg.GetInt64(env.LOGGER_ROTATION_MAX_SIZE_KEY) << 20,
+		Compress:     cfg.GetString(env.LOGGER_ROTATION_COMPRESS_KEY),
+		MaxTotalSize: cfg.GetInt64(env.LOGGER_ROTATION_MAX_TOTAL_KEY) << 20,
+	}
+	logger.InitLogWithRotation(ops.LoggerDir, ops.ServerName, strconv.FormatUint(ops.ServerPort, 10), ops.LoggerConsole, ops.LoggerJson, maxAge, rotation)
+	configureLogLevel()
+	configureLogThrottling()
+	configureRedaction()
+	configureLogSinks(ops.ServerName)
+}
+
+// configureTracing installs the otel TracerProvider when otel.enabled is true
+func configureTracing() {
+	if err := otel.InitDefault(); err != nil {
+		logger.Error("[Bootstrap] otel init error:", err.Error())
+	}
 }
 
 // func (app *Application) StartDb() {
@@ -224,31 +297,36 @@ func (app *Application) StartLogger() {
 // }
 
 func (app *Application) ShutDown() {
-
-	if cfg := app.ConfigCenter; cfg != nil {
-		cfg.Shutdown()
+	health.SetShuttingDown(true)
+	// finish the queued tasks while the clients they use are open, and before the hooks flush what they produce
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+	defer drainCancel()
+	if err := worker.DrainAll(drainCtx); err != nil {
+		logger.Error("[Bootstrap] drain worker pools error:", err.Error())
 	}
-	if registry := app.Registry; registry != nil {
-		registry.Shutdown()
+	if app.Lifecycle != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+		defer cancel()
+		app.Lifecycle.Stop(ctx)
 	}
-	if mq := app.Mq; mq != nil {
-		mq.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+	defer cancel()
+	// flush what the hooks buffer, e.g. the spans of plugins/otel
+	if err := server.EmitEventSync(ctx, server.ShutdownEvent, nil); err != nil {
+		logger.Error("[Bootstrap] shutdown hook error:", err.Error())
 	}
-	if redisClient := app.Redis; redisClient != nil {
-		redisClient.Close()
-	}
-	worker.DefaultWorker.Release()
 	logger.Sync()
+	if err := logger.CloseSinks(); err != nil {
+		fmt.Fprintln(os.Stderr, "[Bootstrap] close log sinks error:", err.Error())
+	}
 }
 
 func (app *Application) StartWebServer(srv server.Server, gracefulShutDown ...func()) {
-	if app.Registry != nil {
-		delayFunction(func() {
-			err := app.Registry.RegisterInstance()
-			if err != nil {
-				logger.Panic("[Bootstrap] registerInstance error", err.Error())
-			}
-		})
+	if ls, ok := srv.(server.LifecycleServer); ok {
+		ls.OnStarted(app.registerInstance)
+		ls.OnShutdown(app.beforeDrain)
+	} else if app.Registry != nil {
+		delayFunction(app.registerInstance)
 	}
 	var gfuncs []func()
 	gfuncs = append(gfuncs, app.ShutDown)
@@ -256,6 +334,31 @@ func (app *Application) StartWebServer(srv server.Server, gracefulShutDown ...fu
 	srv.Run(gfuncs...)
 }
 
+func (app *Application) registerInstance() {
+	if app.Registry == nil {
+		return
+	}
+	err := app.Registry.RegisterInstance()
+	if err != nil {
+		logger.Panic("[Bootstrap] registerInstance error", err.Error())
+	}
+}
+
+// beforeDrain flips readiness and deregisters the instance before the web server drains its connections
+func (app *Application) beforeDrain() {
+	health.SetShuttingDown(true)
+	if d := env.GetInstance().GetDuration(SERVER_SHUTDOWN_READINESS_DELAY_KEY); d > 0 {
+		logger.Info("[Bootstrap] readiness down, waiting:", d)
+		time.Sleep(d)
+	}
+	if app.Registry == nil || app.Lifecycle == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), lifecycle.DefaultStopTimeout)
+	defer cancel()
+	app.Lifecycle.StopComponent(ctx, ComponentRegistry)
+}
+
 func delayFunction(f func()) {
 	time.AfterFunc(1*time.Second, func() {
 		f()
@@ -0,0 +1,242 @@
+package bootstrap
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+)
+
+// Option configures an Application built by New
+type Option func(*builder)
+
+type builder struct {
+	reader    io.Reader
+	fileType  string
+	flagSet   *flag.FlagSet
+	args      []string
+	useEnv    bool
+	envPrefix string
+	settings  map[string]any
+	keys      []string
+}
+
+// the settings bootstrap resolves, with the defaults the legacy flags used
+var bootstrapSettings = []struct {
+	key   string
+	usage string
+	def   any
+}{
+	{env.SERVER_PROFILE_KEY, "server profile:[dev,test,prod...]", nil},
+	{env.SERVER_CONFIGFILE_KEY, "server config file", nil},
+	{env.SERVER_SERVERNAME_KEY, "server name", nil},
+	{env.SERVER_ADDRESS_KEY, "server address", nil},
+	{env.LOGGER_DIR_KEY, "logDir", nil},
+	{env.LOGGER_MAXAGE_KEY, "log maxAge:day   default:7", uint64(7)},
+	{env.LOGGER_CONSOLE, "logger.console enabled:{default:true}", true},
+	{env.LOGGER_JSON, "logger.json enabled:{default:false}", false},
+	{env.FASTHTTP_concurrency_key, "fasthttp.concurrency :{default:256*1024}", 256 * 1024},
+	{env.SERVER_H2C_KEY, "server.h2c default:true", true},
+	{env.SERVER_H2_KEY, "server.h2 default:false", false},
+}
+
+// WithBaseConfig reads the base config, like the reader passed to StartBase
+func WithBaseConfig(reader io.Reader, fileType string) Option {
+	return func(b *builder) {
+		b.reader = reader
+		b.fileType = fileType
+	}
+}
+
+// WithFlagSet registers the bootstrap flags on fs and parses args unless fs is already parsed.
+// Only flags that are explicitly given override other sources.
+func WithFlagSet(fs *flag.FlagSet, args []string) Option {
+	return func(b *builder) {
+		b.flagSet = fs
+		b.args = args
+	}
+}
+
+// WithEnv reads settings from environment variables.
+// With an empty prefix only the bootstrap keys are read, e.g. SERVER_ADDRESS for server.address;
+// with a prefix every PREFIX_A_B variable is mapped to a.b.
+func WithEnv(prefix string) Option {
+	return func(b *builder) {
+		b.useEnv = true
+		b.envPrefix = strings.ToUpper(strings.TrimSuffix(prefix, "_"))
+	}
+}
+
+// WithSetting sets a config key explicitly, it has the highest priority
+func WithSetting(key string, value any) Option {
+	return func(b *builder) {
+		if _, ok := b.settings[key]; !ok {
+			b.keys = append(b.keys, key)
+		}
+		b.settings[key] = value
+	}
+}
+
+func WithProfile(profile string) Option {
+	return WithSetting(env.SERVER_PROFILE_KEY, profile)
+}
+
+func WithServerName(name string) Option {
+	return WithSetting(env.SERVER_SERVERNAME_KEY, name)
+}
+
+func WithAddress(address string) Option {
+	return WithSetting(env.SERVER_ADDRESS_KEY, address)
+}
+
+func WithLoggerDir(dir string) Option {
+	return WithSetting(env.LOGGER_DIR_KEY, dir)
+}
+
+// New builds the Application without touching the global flag set and reports errors instead of panicking.
+// Sources from lowest to highest priority: defaults, base config, profile config, env vars, flags, options.
+func New(opts ...Option) (*Application, error) {
+	b := &builder{fileType: "properties", settings: make(map[string]any)}
+	for _, o := range opts {
+		o(b)
+	}
+	cfg := env.GetInstance()
+	for _, s := range bootstrapSettings {
+		if s.def != nil {
+			cfg.SetDefault(s.key, s.def)
+		}
+	}
+	if b.reader != nil {
+		if err := cfg.SetBaseConfig(b.reader, b.fileType); err != nil {
+			return nil, err
+		}
+	}
+	if err := b.apply(); err != nil {
+		return nil, err
+	}
+	// the profile may come from any source, its file is merged below the pinned values
+	if err := cfg.LoadProfileConfig(cfg.GetString(env.SERVER_PROFILE_KEY), b.fileType); err != nil {
+		return nil, err
+	}
+	bo, err := resolveOptions()
+	if err != nil {
+		return nil, err
+	}
+	app := MthApplication
+	if app == nil {
+		app = &Application{}
+		MthApplication = app
+	}
+	app.BootOptions = bo
+	app.ServerOptions = newServerOptions(bo)
+	logger.Info("[Bootstrap]init BootstrapOptions properties:[Profile=" + bo.Profile + "]" + ",[ServerName=" + bo.ServerName + "],[Bind=" + bo.ServerAddress + "]" + ",[LoggerDir=" + bo.LoggerDir + "]")
+	return app, nil
+}
+
+// apply pins the values from env vars, flags and options in priority order
+func (b *builder) apply() error {
+	cfg := env.GetInstance()
+	if b.useEnv {
+		if len(b.envPrefix) > 0 {
+			for _, kv := range os.Environ() {
+				name, value, _ := strings.Cut(kv, "=")
+				if rest, ok := strings.CutPrefix(name, b.envPrefix+"_"); ok && len(rest) > 0 {
+					key := strings.ToLower(strings.ReplaceAll(rest, "_", "."))
+					cfg.SetWithSource(key, value, env.SourceEnv, name)
+				}
+			}
+		} else {
+			for _, s := range bootstrapSettings {
+				name := strings.ToUpper(strings.ReplaceAll(s.key, ".", "_"))
+				if value, ok := os.LookupEnv(name); ok {
+					cfg.SetWithSource(s.key, value, env.SourceEnv, name)
+				}
+			}
+		}
+	}
+	if fs := b.flagSet; fs != nil {
+		values := make(map[string]*settingFlag)
+		for _, s := range bootstrapSettings {
+			if fs.Lookup(s.key) != nil {
+				continue
+			}
+			v := &settingFlag{}
+			if def, ok := s.def.(bool); ok {
+				v.isBool = true
+				v.value = strconv.FormatBool(def)
+			}
+			values[s.key] = v
+			fs.Var(v, s.key, s.usage)
+		}
+		if !fs.Parsed() {
+			if err := fs.Parse(b.args); err != nil {
+				return err
+			}
+		}
+		fs.Visit(func(f *flag.Flag) {
+			if v, ok := values[f.Name]; ok {
+				cfg.SetWithSource(f.Name, v.value, env.SourceFlag, "")
+			}
+		})
+	}
+	for _, k := range b.keys {
+		cfg.SetWithSource(k, b.settings[k], env.SourceRuntime, "option")
+	}
+	return nil
+}
+
+func resolveOptions() (BootstrapOptions, error) {
+	cfg := env.GetInstance()
+	address := cfg.GetString(env.SERVER_ADDRESS_KEY)
+	if len(address) == 0 {
+		return BootstrapOptions{}, errors.New("server.address is empty")
+	}
+	port, err := parsePort(address)
+	if err != nil {
+		return BootstrapOptions{}, err
+	}
+	source, detail := env.SourceDefault, ""
+	if entry, ok := cfg.Lookup(env.SERVER_ADDRESS_KEY); ok {
+		source, detail = entry.Source, entry.Detail
+	}
+	cfg.SetWithSource(env.SERVER_PORT_KEY, port, source, detail)
+	host, _ := os.Hostname()
+	return BootstrapOptions{
+		ServerAddress:        address,
+		ServerPort:           port,
+		Profile:              cfg.GetString(env.SERVER_PROFILE_KEY),
+		ServerName:           cfg.GetString(env.SERVER_SERVERNAME_KEY),
+		H2C:                  cfg.GetBool(env.SERVER_H2C_KEY),
+		H2:                   cfg.GetBool(env.SERVER_H2_KEY),
+		LoggerDir:            cfg.GetString(env.LOGGER_DIR_KEY),
+		LoggerConsole:        cfg.GetBool(env.LOGGER_CONSOLE),
+		LoggerJson:           cfg.GetBool(env.LOGGER_JSON),
+		Host:                 host,
+		Config:               cfg,
+		MaxServerConcurrency: cfg.GetInt(env.FASTHTTP_concurrency_key),
+	}, nil
+}
+
+// settingFlag keeps the raw flag text, viper converts it when the key is read
+type settingFlag struct {
+	value  string
+	isBool bool
+}
+
+func (f *settingFlag) String() string {
+	return f.value
+}
+
+func (f *settingFlag) Set(s string) error {
+	f.value = s
+	return nil
+}
+
+func (f *settingFlag) IsBoolFlag() bool {
+	return f.isBool
+}
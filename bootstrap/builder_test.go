@@ -0,0 +1,32 @@
+package bootstrap
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	"github.com/skirrund/gcloud/bootstrap/env"
+)
+
+func TestNew(t *testing.T) {
+	t.Setenv("GCLOUD_TEST_SERVER_NAME", "from-env")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	app, err := New(
+		WithBaseConfig(bytes.NewReader([]byte("server.address=:8080\nserver.name=base\n")), "properties"),
+		WithEnv("GCLOUD_TEST"),
+		WithFlagSet(fs, []string{"-server.address=127.0.0.1:9090", "-logger.console=false"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bo := app.BootOptions
+	if bo.ServerAddress != "127.0.0.1:9090" || bo.ServerPort != 9090 || bo.ServerName != "from-env" || bo.LoggerConsole || !bo.H2C {
+		t.Fatalf("unexpected options %+v", bo)
+	}
+	if entry, _ := env.GetInstance().Lookup(env.SERVER_ADDRESS_KEY); entry.Source != env.SourceFlag {
+		t.Fatalf("server.address source %s", entry.Source)
+	}
+	if _, err := New(WithAddress("localhost")); err == nil {
+		t.Fatal("expected error for address without port")
+	}
+}
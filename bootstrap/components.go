@@ -0,0 +1,112 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/skirrund/gcloud/bootstrap/lifecycle"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/mq"
+	"github.com/skirrund/gcloud/registry"
+	"github.com/skirrund/gcloud/server/health"
+)
+
+const (
+	ComponentConfig   = "config"
+	ComponentRedis    = "redis"
+	ComponentMq       = "mq"
+	ComponentRegistry = "registry"
+
+	DefaultShutdownTimeout = 30 * time.Second
+	// how long readiness reports DOWN before the registry is deregistered and connections are drained
+	SERVER_SHUTDOWN_READINESS_DELAY_KEY = "server.shutdown.readinessDelay"
+)
+
+// registerComponents wraps the clients passed to Bootstrap as lifecycle components
+func (app *Application) registerComponents() {
+	if app.Lifecycle == nil {
+		app.Lifecycle = lifecycle.New()
+	}
+	var deps []lifecycle.Option
+	if cfg := app.ConfigCenter; cfg != nil {
+		app.register(lifecycle.NewComponent(ComponentConfig, nil, func(ctx context.Context) error {
+			return cfg.Shutdown()
+		}, nil))
+		deps = append(deps, lifecycle.DependsOn(ComponentConfig))
+	}
+	if redisClient := app.Redis; redisClient != nil {
+		app.register(lifecycle.NewComponent(ComponentRedis, nil, func(ctx context.Context) error {
+			redisClient.Close()
+			return nil
+		}, func() lifecycle.State {
+			if redisClient.Ping() != nil {
+				return lifecycle.StateDown
+			}
+			return lifecycle.StateUp
+		}), deps...)
+	}
+	if mqClient := app.Mq; mqClient != nil {
+		var state func() lifecycle.State
+		if p, ok := mqClient.(mq.Pinger); ok {
+			state = pingState(p.Ping)
+		}
+		app.register(lifecycle.NewComponent(ComponentMq, nil, func(ctx context.Context) error {
+			mqClient.Close()
+			return nil
+		}, state), deps...)
+	}
+	if reg := app.Registry; reg != nil {
+		var state func() lifecycle.State
+		if p, ok := reg.(registry.Pinger); ok {
+			state = pingState(p.Ping)
+		}
+		app.register(lifecycle.NewComponent(ComponentRegistry, nil, func(ctx context.Context) error {
+			reg.Shutdown()
+			return nil
+		}, state), deps...)
+	}
+}
+
+// pingState reports a started client down while its ping fails,
+// clients that cannot be pinged stay up until they are stopped
+func pingState(ping func(ctx context.Context) error) func() lifecycle.State {
+	return func() lifecycle.State {
+		ctx, cancel := context.WithTimeout(context.Background(), health.DefaultTimeout)
+		defer cancel()
+		if ping(ctx) != nil {
+			return lifecycle.StateDown
+		}
+		return lifecycle.StateUp
+	}
+}
+
+func (app *Application) register(c lifecycle.Component, opts ...lifecycle.Option) {
+	if err := app.Lifecycle.Register(c, opts...); err != nil {
+		logger.Warn("[Bootstrap] register component:", err.Error())
+		return
+	}
+	name := c.Name()
+	// redis registers its own ping probe
+	if name != ComponentRedis {
+		health.Register(name, health.KindReadiness, app.componentCheck(name))
+	}
+}
+
+func (app *Application) componentCheck(name string) health.Check {
+	return func(ctx context.Context) error {
+		if s := app.Lifecycle.HealthOf(name); s != lifecycle.StateUp {
+			return fmt.Errorf("component %s is %s", name, s)
+		}
+		return nil
+	}
+}
+
+// RegisterComponent adds a custom component to the application lifecycle.
+// Components registered before Bootstrap are started by it, later ones need app.Lifecycle.Start.
+func (app *Application) RegisterComponent(c lifecycle.Component, opts ...lifecycle.Option) error {
+	if app.Lifecycle == nil {
+		app.Lifecycle = lifecycle.New()
+	}
+	return app.Lifecycle.Register(c, opts...)
+}
@@ -0,0 +1,37 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/skirrund/gcloud/bootstrap/lifecycle"
+	"github.com/skirrund/gcloud/mq"
+)
+
+type pingMq struct {
+	mq.IClient
+	err error
+}
+
+func (c *pingMq) Ping(ctx context.Context) error {
+	return c.err
+}
+
+func (c *pingMq) Close() {}
+
+func TestMqComponentHealth(t *testing.T) {
+	client := &pingMq{}
+	app := &Application{Mq: client}
+	app.registerComponents()
+	if err := app.Lifecycle.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s := app.Lifecycle.HealthOf(ComponentMq); s != lifecycle.StateUp {
+		t.Fatalf("connected client is %s", s)
+	}
+	client.err = errors.New("disconnected")
+	if s := app.Lifecycle.HealthOf(ComponentMq); s != lifecycle.StateDown {
+		t.Fatalf("disconnected client is %s", s)
+	}
+}
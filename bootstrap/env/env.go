@@ -5,6 +5,8 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/skirrund/gcloud/parser"
 	"github.com/skirrund/gcloud/server"
@@ -14,32 +16,67 @@ import (
 )
 
 type env struct {
-	config *viper.Viper
-	base   map[string]any
+	config    *viper.Viper
+	base      map[string]any
+	mu        sync.RWMutex
+	origins   map[string]origin
+	overrides map[string]struct{}
 }
 
 const (
-	SERVER_ADDRESS_KEY       = "server.address"
-	SERVER_PORT_KEY          = "server.port"
-	SERVER_PROFILE_KEY       = "server.profile"
-	SERVER_CONFIGFILE_KEY    = "server.config.file"
-	SERVER_SERVERNAME_KEY    = "server.name"
-	SERVER_H2C_KEY           = "server.h2c"
-	SERVER_H2_KEY            = "server.h2"
-	LOGGER_DIR_KEY           = "logger.dir"
-	LOGGER_MAXAGE_KEY        = "logger.maxAge"
-	LOGGER_CONSOLE           = "logger.console"
-	LOGGER_JSON              = "logger.json"
-	ZIPKIN_URL_KEY           = "zipkin.url"
-	FASTHTTP_concurrency_key = "fasthttp.concurrency"
+	SERVER_ADDRESS_KEY                = "server.address"
+	SERVER_PORT_KEY                   = "server.port"
+	SERVER_PROFILE_KEY                = "server.profile"
+	SERVER_CONFIGFILE_KEY             = "server.config.file"
+	SERVER_SERVERNAME_KEY             = "server.name"
+	SERVER_H2C_KEY                    = "server.h2c"
+	SERVER_H2_KEY                     = "server.h2"
+	SERVER_READ_TIMEOUT_KEY           = "server.readTimeout"
+	SERVER_READ_HEADER_TIMEOUT_KEY    = "server.readHeaderTimeout"
+	SERVER_WRITE_TIMEOUT_KEY          = "server.writeTimeout"
+	SERVER_IDLE_TIMEOUT_KEY           = "server.idleTimeout"
+	SERVER_SHUTDOWN_TIMEOUT_KEY       = "server.shutdownTimeout"
+	SERVER_MAX_HEADER_BYTES_KEY       = "server.maxHeaderBytes"
+	SERVER_MAX_REQUEST_BODY_SIZE_KEY  = "server.maxRequestBodySize"
+	SERVER_MAX_CONCURRENT_STREAMS_KEY = "server.maxConcurrentStreams"
+	SERVER_TLS_CERT_FILE_KEY          = "server.tls.certFile"
+	SERVER_TLS_KEY_FILE_KEY           = "server.tls.keyFile"
+	LOGGER_DIR_KEY                    = "logger.dir"
+	LOGGER_MAXAGE_KEY                 = "logger.maxAge"
+	LOGGER_CONSOLE                    = "logger.console"
+	LOGGER_JSON                       = "logger.json"
+	LOGGER_LEVEL_KEY                  = "logger.level"
+	LOGGER_LEVELS_KEY                 = "logger.levels"
+	LOGGER_DEMOTE_KEY                 = "logger.demote"
+	LOGGER_SAMPLING_KEY               = "logger.sampling"
+	LOGGER_RATE_LIMITS_KEY            = "logger.rateLimits"
+	LOGGER_ROTATION_TIME_KEY          = "logger.rotation.time"
+	LOGGER_ROTATION_MAX_SIZE_KEY      = "logger.rotation.maxSizeMB"
+	LOGGER_ROTATION_COMPRESS_KEY      = "logger.rotation.compress"
+	LOGGER_ROTATION_MAX_TOTAL_KEY     = "logger.rotation.maxTotalSizeMB"
+	LOGGER_SINKS_RING_KEY             = "logger.sinks.ring"
+	LOGGER_SINKS_SYSLOG_KEY           = "logger.sinks.syslog"
+	LOGGER_SINKS_HTTP_KEY             = "logger.sinks.http"
+	LOGGER_REDACT_ENABLED_KEY         = "logger.redact.enabled"
+	LOGGER_REDACT_LOGGER_KEY          = "logger.redact.logger"
+	LOGGER_REDACT_FIELDS_KEY          = "logger.redact.fields"
+	LOGGER_REDACT_PATHS_KEY           = "logger.redact.paths"
+	LOGGER_REDACT_DETECTORS_KEY       = "logger.redact.detectors"
+	LOGGER_REDACT_PATTERNS_KEY        = "logger.redact.patterns"
+	WORKER_POOLS_KEY                  = "worker.pools"
+	ZIPKIN_URL_KEY                    = "zipkin.url"
+	ZIPKIN_SAMPLE_RATE_KEY            = "zipkin.sampleRate"
+	FASTHTTP_concurrency_key          = "fasthttp.concurrency"
 )
 
 var e *env
 
 func init() {
 	e = &env{
-		config: parser.NewDefaultParser(),
-		base:   make(map[string]any),
+		config:    parser.NewDefaultParser(),
+		base:      make(map[string]any),
+		origins:   make(map[string]origin),
+		overrides: make(map[string]struct{}),
 	}
 	server.RegisterEventHookFirst(server.ConfigChangeEvent, e.MergeConfig)
 }
@@ -49,6 +86,13 @@ func GetInstance() *env {
 }
 
 func (e *env) LoadProfileBaseConfig(profile string, configType string) {
+	if err := e.LoadProfileConfig(profile, configType); err != nil {
+		panic(err)
+	}
+}
+
+// LoadProfileConfig merges conf/bootstrap-{profile}.{configType} (or server.config.file) into the config
+func (e *env) LoadProfileConfig(profile string, configType string) error {
 	cfgPath := e.GetString(SERVER_CONFIGFILE_KEY)
 	path, _ := os.Getwd()
 	if len(cfgPath) == 0 {
@@ -70,17 +114,20 @@ func (e *env) LoadProfileBaseConfig(profile string, configType string) {
 				err = pcfg.ReadConfig(bytes2.NewReader(contents))
 				if err == nil {
 					settings := pcfg.AllSettings()
+					changed := e.changedKeys(settings)
 					e.config.MergeConfigMap(settings)
+					e.markOrigin(changed, SourceProfile, cfgPath)
 					e.base = e.config.AllSettings()
 				} else {
 					logger.Error("[ENV] load config file profile error:", err.Error())
-					panic(err)
+					return err
 				}
 			}
 		} else {
 			logger.Error("[ENV] load config file profile error:", err.Error())
 		}
 	}
+	return nil
 }
 
 func (e *env) SetBaseConfig(reader io.Reader, configType string) error {
@@ -93,21 +140,30 @@ func (e *env) SetBaseConfig(reader io.Reader, configType string) error {
 		return err
 	}
 	e.base = cfg.AllSettings()
+	e.markOrigin(e.changedKeys(e.base), SourceBase, "")
 	return nil
 }
 
 func (e *env) MergeConfig(eventType server.EventName, eventInfo any) (err error) {
 	logger.Info("[ENV] config changed")
+	var settings map[string]any
 	if cfg, ok := eventInfo.(*viper.Viper); ok {
 		logger.Info("[ENV] config changed type viper.Viper")
-		err = e.config.MergeConfigMap(cfg.AllSettings())
+		settings = cfg.AllSettings()
 	}
 	if cfg, ok := eventInfo.(map[string]any); ok {
-		err = e.config.MergeConfigMap(cfg)
+		settings = cfg
 	}
+	if settings == nil {
+		return
+	}
+	changed := e.changedKeys(settings)
+	err = e.config.MergeConfigMap(settings)
 	if err != nil {
 		logger.Error("[ENV] config changed Error:" + err.Error())
+		return
 	}
+	e.markOrigin(changed, SourceEvent, string(eventType))
 	return
 }
 
@@ -125,8 +181,13 @@ func (nc *env) Get(key string) any {
 	return nc.config.Get(key)
 }
 
+// SetDefault sets the lowest priority value of key, used when no source provides it
+func (nc *env) SetDefault(key string, value any) {
+	nc.config.SetDefault(key, value)
+}
+
 func (nc *env) Set(key string, value any) {
-	nc.config.Set(key, value)
+	nc.SetWithSource(key, value, SourceRuntime, "")
 }
 
 func (e *env) GetStringWithDefault(key string, defaultString string) string {
@@ -190,9 +251,26 @@ func (nc *env) GetUintWithDefault(key string, defaultUint uint) uint {
 	}
 	return v
 }
+func (nc *env) GetDuration(key string) time.Duration {
+	return nc.config.GetDuration(key)
+}
+func (nc *env) GetDurationWithDefault(key string, defaultDuration time.Duration) time.Duration {
+	v := nc.GetDuration(key)
+	if v == 0 {
+		return defaultDuration
+	}
+	return v
+}
 func (nc *env) GetBool(key string) bool {
 	return nc.config.GetBool(key)
 }
+
+func (nc *env) GetBoolWithDefault(key string, defaultBool bool) bool {
+	if nc.Get(key) == nil {
+		return defaultBool
+	}
+	return nc.GetBool(key)
+}
 func (nc *env) GetFloat64(key string) float64 {
 	return nc.config.GetFloat64(key)
 }
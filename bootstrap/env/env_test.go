@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/skirrund/gcloud/parser"
+	"github.com/skirrund/gcloud/server"
 )
 
 func TestParseJavaproperties(t *testing.T) {
@@ -26,3 +27,20 @@ func TestParseJavaproperties(t *testing.T) {
 	s := pcfg.GetStringSlice("datasource.queryFields")
 	fmt.Println(s)
 }
+
+func TestConfigSources(t *testing.T) {
+	e := GetInstance()
+	e.SetBaseConfig(bytes.NewReader([]byte("a.b=1\na.c=2\n")), "properties")
+	e.MergeConfig(server.ConfigChangeEvent, map[string]any{"a": map[string]any{"c": "3"}})
+	e.SetWithSource("a.d", "4", SourceFlag, "")
+	want := map[string]Source{"a.b": SourceBase, "a.c": SourceEvent, "a.d": SourceFlag}
+	for k, s := range want {
+		entry, ok := e.Lookup(k)
+		if !ok || entry.Source != s {
+			t.Fatalf("%s: source=%v,want %v", k, entry.Source, s)
+		}
+	}
+	if v := e.GetString("a.c"); v != "3" {
+		t.Fatalf("a.c=%s", v)
+	}
+}
@@ -0,0 +1,126 @@
+package env
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Source describes where the effective value of a config key came from.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceBase    Source = "base"
+	SourceProfile Source = "profile"
+	SourceFlag    Source = "flag"
+	SourceEnv     Source = "env"
+	SourceEvent   Source = "event"
+	SourceRuntime Source = "runtime"
+)
+
+type origin struct {
+	source    Source
+	detail    string
+	updatedAt time.Time
+}
+
+// Entry is a snapshot of one effective config key.
+type Entry struct {
+	Key       string    `json:"key"`
+	Value     any       `json:"value"`
+	Source    Source    `json:"source"`
+	Detail    string    `json:"detail,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// flatten turns viper's nested settings into dotted keys
+func flatten(prefix string, settings map[string]any, out map[string]any) map[string]any {
+	if out == nil {
+		out = make(map[string]any)
+	}
+	for k, v := range settings {
+		key := strings.ToLower(k)
+		if len(prefix) > 0 {
+			key = prefix + "." + key
+		}
+		if m, ok := v.(map[string]any); ok {
+			flatten(key, m, out)
+		} else {
+			out[key] = v
+		}
+	}
+	return out
+}
+
+// changedKeys returns the keys of settings whose value differs from the current config.
+// keys pinned by Set are skipped because viper overrides always win over merged config.
+func (e *env) changedKeys(settings map[string]any) []string {
+	flat := flatten("", settings, nil)
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	keys := make([]string, 0, len(flat))
+	for k, v := range flat {
+		if _, ok := e.overrides[k]; ok {
+			continue
+		}
+		if _, ok := e.origins[k]; ok && reflect.DeepEqual(e.config.Get(k), v) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (e *env) markOrigin(keys []string, source Source, detail string) {
+	now := time.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, k := range keys {
+		e.origins[k] = origin{source: source, detail: detail, updatedAt: now}
+	}
+}
+
+// SetWithSource sets an override value and records where it came from.
+func (e *env) SetWithSource(key string, value any, source Source, detail string) {
+	k := strings.ToLower(key)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	old, tracked := e.origins[k]
+	unchanged := reflect.DeepEqual(e.config.Get(k), value)
+	e.config.Set(key, value)
+	e.overrides[k] = struct{}{}
+	if tracked && unchanged && old.source == source && old.detail == detail {
+		return
+	}
+	e.origins[k] = origin{source: source, detail: detail, updatedAt: time.Now()}
+}
+
+// Lookup returns the effective value of key together with its recorded origin.
+func (e *env) Lookup(key string) (Entry, bool) {
+	k := strings.ToLower(key)
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	o, ok := e.origins[k]
+	return Entry{Key: k, Value: e.config.Get(k), Source: o.source, Detail: o.detail, UpdatedAt: o.updatedAt}, ok
+}
+
+// Entries returns all effective keys sorted by name together with their origin.
+func (e *env) Entries() []Entry {
+	keys := e.config.AllKeys()
+	sort.Strings(keys)
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	entries := make([]Entry, 0, len(keys))
+	for _, k := range keys {
+		entry := Entry{Key: k, Value: e.config.Get(k), Source: SourceDefault}
+		if o, ok := e.origins[k]; ok {
+			entry.Source = o.source
+			entry.Detail = o.detail
+			entry.UpdatedAt = o.updatedAt
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,40 @@
+package lifecycle
+
+import "context"
+
+type funcComponent struct {
+	name   string
+	start  func(ctx context.Context) error
+	stop   func(ctx context.Context) error
+	health func() State
+}
+
+// NewComponent adapts plain functions to a Component, any of them may be nil
+func NewComponent(name string, start, stop func(ctx context.Context) error, health func() State) Component {
+	return &funcComponent{name: name, start: start, stop: stop, health: health}
+}
+
+func (f *funcComponent) Name() string {
+	return f.name
+}
+
+func (f *funcComponent) Start(ctx context.Context) error {
+	if f.start == nil {
+		return nil
+	}
+	return f.start(ctx)
+}
+
+func (f *funcComponent) Stop(ctx context.Context) error {
+	if f.stop == nil {
+		return nil
+	}
+	return f.stop(ctx)
+}
+
+func (f *funcComponent) Health() State {
+	if f.health == nil {
+		return StateUp
+	}
+	return f.health()
+}
@@ -0,0 +1,272 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/skirrund/gcloud/logger"
+)
+
+type State string
+
+const (
+	StateNew      State = "NEW"
+	StateStarting State = "STARTING"
+	StateUp       State = "UP"
+	StateDown     State = "DOWN"
+	StateStopping State = "STOPPING"
+	StateStopped  State = "STOPPED"
+
+	DefaultStartTimeout = 30 * time.Second
+	DefaultStopTimeout  = 10 * time.Second
+)
+
+// Component is a managed part of the application such as a registry, mq or redis client.
+type Component interface {
+	Name() string
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Health() State
+}
+
+type entry struct {
+	c            Component
+	deps         []string
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+	state        State
+}
+
+type Option func(*entry)
+
+// DependsOn declares components that must be started before this one and stopped after it
+func DependsOn(names ...string) Option {
+	return func(e *entry) {
+		e.deps = append(e.deps, names...)
+	}
+}
+
+func StartTimeout(d time.Duration) Option {
+	return func(e *entry) {
+		e.startTimeout = d
+	}
+}
+
+func StopTimeout(d time.Duration) Option {
+	return func(e *entry) {
+		e.stopTimeout = d
+	}
+}
+
+// Manager starts components in dependency order and stops them in reverse order.
+type Manager struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	order   []string
+	started []string
+}
+
+func New() *Manager {
+	return &Manager{entries: make(map[string]*entry)}
+}
+
+func (m *Manager) Register(c Component, opts ...Option) error {
+	if c == nil {
+		return errors.New("[lifecycle] component is nil")
+	}
+	e := &entry{
+		c:            c,
+		startTimeout: DefaultStartTimeout,
+		stopTimeout:  DefaultStopTimeout,
+		state:        StateNew,
+	}
+	for _, o := range opts {
+		o(e)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name := c.Name()
+	if _, ok := m.entries[name]; ok {
+		return fmt.Errorf("[lifecycle] component %s already registered", name)
+	}
+	m.entries[name] = e
+	m.order = append(m.order, name)
+	return nil
+}
+
+// sortNolock returns the registered components in dependency order
+func (m *Manager) sortNolock() ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make(map[string]int, len(m.entries))
+	sorted := make([]string, 0, len(m.entries))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		e, ok := m.entries[name]
+		if !ok {
+			return fmt.Errorf("[lifecycle] unknown component %s required by %v", name, path)
+		}
+		switch marks[name] {
+		case visiting:
+			return fmt.Errorf("[lifecycle] dependency cycle %v -> %s", path, name)
+		case visited:
+			return nil
+		}
+		marks[name] = visiting
+		for _, d := range e.deps {
+			if err := visit(d, append(path[:len(path):len(path)], name)); err != nil {
+				return err
+			}
+		}
+		marks[name] = visited
+		sorted = append(sorted, name)
+		return nil
+	}
+	for _, name := range m.order {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// Start starts every component that has not been started yet.
+// If one fails, the components started by this call are stopped again in reverse order.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sorted, err := m.sortNolock()
+	if err != nil {
+		return err
+	}
+	var startedNow []string
+	for _, name := range sorted {
+		e := m.entries[name]
+		if e.state != StateNew {
+			continue
+		}
+		e.state = StateStarting
+		logger.Info("[lifecycle] starting component:", name)
+		if err := run(ctx, e.startTimeout, e.c.Start); err != nil {
+			e.state = StateDown
+			logger.Error("[lifecycle] start component error:", name, ",", err.Error())
+			for i := len(startedNow) - 1; i >= 0; i-- {
+				m.stopNolock(context.WithoutCancel(ctx), startedNow[i])
+			}
+			return fmt.Errorf("[lifecycle] start %s: %w", name, err)
+		}
+		e.state = StateUp
+		startedNow = append(startedNow, name)
+		m.started = append(m.started, name)
+	}
+	return nil
+}
+
+// Stop stops all started components in reverse start order
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var errs []error
+	for i := len(m.started) - 1; i >= 0; i-- {
+		if err := m.stopNolock(ctx, m.started[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StopComponent stops a single component ahead of the others, e.g. deregistering before draining
+func (m *Manager) StopComponent(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stopNolock(ctx, name)
+}
+
+func (m *Manager) stopNolock(ctx context.Context, name string) error {
+	e, ok := m.entries[name]
+	if !ok || e.state != StateUp {
+		return nil
+	}
+	e.state = StateStopping
+	logger.Info("[lifecycle] stopping component:", name)
+	err := run(ctx, e.stopTimeout, e.c.Stop)
+	e.state = StateStopped
+	if err != nil {
+		logger.Error("[lifecycle] stop component error:", name, ",", err.Error())
+		return fmt.Errorf("[lifecycle] stop %s: %w", name, err)
+	}
+	return nil
+}
+
+// State returns the lifecycle state of the named component
+func (m *Manager) State(name string) State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[name]; ok {
+		return e.state
+	}
+	return ""
+}
+
+// HealthOf returns the health of one component, asking it directly once it is started
+func (m *Manager) HealthOf(name string) State {
+	m.mu.Lock()
+	e, ok := m.entries[name]
+	if !ok {
+		m.mu.Unlock()
+		return ""
+	}
+	state := e.state
+	m.mu.Unlock()
+	if state == StateUp {
+		return e.c.Health()
+	}
+	return state
+}
+
+// Health returns the state of every component, asking started components for their own health
+func (m *Manager) Health() map[string]State {
+	m.mu.Lock()
+	entries := make(map[string]*entry, len(m.entries))
+	states := make(map[string]State, len(m.entries))
+	for name, e := range m.entries {
+		entries[name] = e
+		states[name] = e.state
+	}
+	m.mu.Unlock()
+	for name, e := range entries {
+		if states[name] == StateUp {
+			states[name] = e.c.Health()
+		}
+	}
+	return states
+}
+
+func run(ctx context.Context, timeout time.Duration, f func(ctx context.Context) error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- f(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
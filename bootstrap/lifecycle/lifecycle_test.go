@@ -0,0 +1,71 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStartStopOrder(t *testing.T) {
+	var events []string
+	comp := func(name string, startErr error) Component {
+		return NewComponent(name, func(ctx context.Context) error {
+			events = append(events, "start:"+name)
+			return startErr
+		}, func(ctx context.Context) error {
+			events = append(events, "stop:"+name)
+			return nil
+		}, nil)
+	}
+	m := New()
+	m.Register(comp("registry", nil), DependsOn("config", "redis"))
+	m.Register(comp("redis", nil), DependsOn("config"))
+	m.Register(comp("config", nil))
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"start:config", "start:redis", "start:registry", "stop:registry", "stop:redis", "stop:config"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("got %v,want %v", events, want)
+	}
+
+	events = nil
+	m = New()
+	m.Register(comp("config", nil))
+	m.Register(comp("mq", errors.New("boom")), DependsOn("config"))
+	if err := m.Start(context.Background()); err == nil {
+		t.Fatal("expected start error")
+	}
+	want = []string{"start:config", "start:mq", "stop:config"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("got %v,want %v", events, want)
+	}
+	if s := m.State("mq"); s != StateDown {
+		t.Fatalf("mq state %s", s)
+	}
+}
+
+func TestStartTimeout(t *testing.T) {
+	m := New()
+	m.Register(NewComponent("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}, nil, nil), StartTimeout(10*time.Millisecond))
+	if err := m.Start(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded,got %v", err)
+	}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	m := New()
+	m.Register(NewComponent("a", nil, nil, nil), DependsOn("b"))
+	m.Register(NewComponent("b", nil, nil, nil), DependsOn("a"))
+	if err := m.Start(context.Background()); err == nil {
+		t.Fatal("expected cycle error")
+	}
+}
@@ -0,0 +1,82 @@
+package bootstrap
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/server"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	levelHookOnce sync.Once
+	levelMu       sync.Mutex
+	// appliedLevels is the config applied last, a config change that leaves it alone
+	// keeps the levels set at runtime, e.g. through the admin endpoint
+	appliedLevels string
+)
+
+// configureLogLevel applies logger.level and logger.levels.<name> and re-applies them when they change.
+// Names are lowercased by the config, nested keys are joined with ".", e.g. logger.levels.cache.redis=debug.
+func configureLogLevel() {
+	applyLogLevel()
+	levelHookOnce.Do(func() {
+		server.RegisterEventHook(server.ConfigChangeEvent, func(eventType server.EventName, eventInfo any) error {
+			applyLogLevel()
+			return nil
+		})
+	})
+}
+
+func applyLogLevel() {
+	cfg := env.GetInstance()
+	base := cfg.GetStringWithDefault(env.LOGGER_LEVEL_KEY, "info")
+	var raw map[string]any
+	if err := cfg.UnmarshalKey(env.LOGGER_LEVELS_KEY, &raw); err != nil {
+		logger.Error("[Bootstrap] logger.levels config error:", err.Error())
+		return
+	}
+	names := make(map[string]string)
+	flattenLevels("", raw, names)
+
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	key := fmt.Sprint(base, names)
+	if key == appliedLevels {
+		return
+	}
+	lvl, err := logger.ParseLevel(base)
+	if err != nil {
+		logger.Error("[Bootstrap] logger.level config error:", err.Error())
+		return
+	}
+	overrides := make(map[string]zapcore.Level, len(names))
+	for name, text := range names {
+		l, err := logger.ParseLevel(text)
+		if err != nil {
+			logger.Error("[Bootstrap] logger.levels config error:", name, ",", err.Error())
+			return
+		}
+		overrides[name] = l
+	}
+	logger.SetLevel(lvl)
+	logger.SetNamedLevels(overrides)
+	appliedLevels = key
+	logger.Info("[Bootstrap] logger level:", lvl, ",overrides:", names)
+}
+
+func flattenLevels(prefix string, m map[string]any, out map[string]string) {
+	for k, v := range m {
+		name := k
+		if len(prefix) > 0 {
+			name = prefix + "." + k
+		}
+		if sub, ok := v.(map[string]any); ok {
+			flattenLevels(name, sub, out)
+			continue
+		}
+		out[name] = fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,77 @@
+package bootstrap
+
+import (
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/server/metrics"
+)
+
+const (
+	SyslogSinkName = "syslog"
+	HTTPSinkName   = "http"
+)
+
+// configureLogSinks adds the sinks configured under logger.sinks.ring, logger.sinks.syslog and
+// logger.sinks.http. Each takes level, queueSize, batchSize, flushInterval, block, maxRetries and retryBackoff
+// besides its own keys.
+// Sinks are added once at startup, config changes don't affect them.
+func configureLogSinks(serviceName string) {
+	cfg := env.GetInstance()
+	added := false
+	if key := env.LOGGER_SINKS_RING_KEY; cfg.GetBool(key + ".enabled") {
+		ring := logger.NewRingSink(cfg.GetIntWithDefault(key+".size", 1000))
+		added = addLogSink(logger.RingSinkName, ring, key) || added
+	}
+	if key := env.LOGGER_SINKS_SYSLOG_KEY; len(cfg.GetString(key+".address")) > 0 {
+		s := logger.NewSyslogSink(logger.SyslogOptions{
+			Network:  cfg.GetString(key + ".network"),
+			Address:  cfg.GetString(key + ".address"),
+			Facility: cfg.GetInt(key + ".facility"),
+			AppName:  cfg.GetStringWithDefault(key+".appName", serviceName),
+		})
+		added = addLogSink(SyslogSinkName, s, key) || added
+	}
+	if key := env.LOGGER_SINKS_HTTP_KEY; len(cfg.GetString(key+".url")) > 0 {
+		s, err := logger.NewHTTPSink(logger.HTTPSinkOptions{
+			URL:     cfg.GetString(key + ".url"),
+			Format:  cfg.GetString(key + ".format"),
+			Headers: cfg.GetStringMapString(key + ".headers"),
+			Labels:  cfg.GetStringMapString(key + ".labels"),
+			Index:   cfg.GetString(key + ".index"),
+		})
+		if err != nil {
+			logger.Error("[Bootstrap] logger.sinks.http config error:", err.Error())
+		} else {
+			added = addLogSink(HTTPSinkName, s, key) || added
+		}
+	}
+	if added {
+		metrics.RegisterLogSinks()
+	}
+}
+
+func addLogSink(name string, s logger.Sink, key string) bool {
+	cfg := env.GetInstance()
+	opts := logger.SinkOptions{
+		QueueSize:     cfg.GetInt(key + ".queueSize"),
+		BatchSize:     cfg.GetInt(key + ".batchSize"),
+		FlushInterval: cfg.GetDuration(key + ".flushInterval"),
+		Block:         cfg.GetBool(key + ".block"),
+		MaxRetries:    cfg.GetInt(key + ".maxRetries"),
+		RetryBackoff:  cfg.GetDuration(key + ".retryBackoff"),
+	}
+	if lvl := cfg.GetString(key + ".level"); len(lvl) > 0 {
+		l, err := logger.ParseLevel(lvl)
+		if err != nil {
+			logger.Error("[Bootstrap] "+key+".level config error:", err.Error())
+			return false
+		}
+		opts.Level = l
+	}
+	if err := logger.AddSink(name, s, opts); err != nil {
+		logger.Error("[Bootstrap] add log sink error:", err.Error())
+		return false
+	}
+	logger.Info("[Bootstrap] log sink added:", name)
+	return true
+}
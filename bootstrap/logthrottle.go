@@ -0,0 +1,53 @@
+package bootstrap
+
+import (
+	"sync"
+	"time"
+
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/server"
+	"github.com/skirrund/gcloud/server/metrics"
+)
+
+var throttleHookOnce sync.Once
+
+// configureLogThrottling applies logger.demote, logger.sampling and logger.rateLimits and re-applies
+// them on every config change, e.g.
+//
+//	logger:
+//	  demote: [lb, http]
+//	  sampling: {enabled: true, tick: 1s, first: 100, thereafter: 100}
+//	  rateLimits:
+//	    - {key: "[LB] get instance", rate: 10, burst: 50}
+func configureLogThrottling() {
+	applyLogThrottling()
+	throttleHookOnce.Do(func() {
+		metrics.RegisterLogThrottling()
+		server.RegisterEventHook(server.ConfigChangeEvent, func(eventType server.EventName, eventInfo any) error {
+			applyLogThrottling()
+			return nil
+		})
+	})
+}
+
+func applyLogThrottling() {
+	cfg := env.GetInstance()
+	logger.SetDemoted(cfg.GetStringSlice(env.LOGGER_DEMOTE_KEY)...)
+	key := env.LOGGER_SAMPLING_KEY
+	if cfg.GetBool(key + ".enabled") {
+		logger.SetSampling(&logger.Sampling{
+			Tick:       cfg.GetDurationWithDefault(key+".tick", time.Second),
+			First:      cfg.GetIntWithDefault(key+".first", 100),
+			Thereafter: cfg.GetIntWithDefault(key+".thereafter", 100),
+		})
+	} else {
+		logger.SetSampling(nil)
+	}
+	var limits []logger.RateLimit
+	if err := cfg.UnmarshalKey(env.LOGGER_RATE_LIMITS_KEY, &limits); err != nil {
+		logger.Error("[Bootstrap] logger.rateLimits config error:", err.Error())
+		return
+	}
+	logger.SetRateLimits(limits)
+}
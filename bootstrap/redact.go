@@ -0,0 +1,53 @@
+package bootstrap
+
+import (
+	"sync"
+
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/server"
+	"github.com/skirrund/gcloud/utils/redact"
+)
+
+var redactHookOnce sync.Once
+
+// configureRedaction applies logger.redact.* and re-applies it on every config change,
+// redaction is off unless logger.redact.enabled is true
+func configureRedaction() {
+	applyRedaction()
+	redactHookOnce.Do(func() {
+		server.RegisterEventHook(server.ConfigChangeEvent, func(eventType server.EventName, eventInfo any) error {
+			applyRedaction()
+			return nil
+		})
+	})
+}
+
+func applyRedaction() {
+	cfg := env.GetInstance()
+	if !cfg.GetBool(env.LOGGER_REDACT_ENABLED_KEY) {
+		redact.SetDefault(nil)
+		logger.SetRedactor(nil)
+		return
+	}
+	rc := redact.DefaultConfig()
+	if cfg.Get(env.LOGGER_REDACT_FIELDS_KEY) != nil {
+		rc.Fields = cfg.GetStringSlice(env.LOGGER_REDACT_FIELDS_KEY)
+	}
+	if cfg.Get(env.LOGGER_REDACT_DETECTORS_KEY) != nil {
+		rc.Detectors = cfg.GetStringSlice(env.LOGGER_REDACT_DETECTORS_KEY)
+	}
+	rc.Paths = cfg.GetStringSlice(env.LOGGER_REDACT_PATHS_KEY)
+	rc.Patterns = cfg.GetStringMapString(env.LOGGER_REDACT_PATTERNS_KEY)
+	r, err := redact.New(rc)
+	if err != nil {
+		logger.Error("[Bootstrap] logger.redact config error:", err.Error())
+		return
+	}
+	redact.SetDefault(r)
+	if cfg.GetBoolWithDefault(env.LOGGER_REDACT_LOGGER_KEY, true) {
+		logger.SetRedactor(r.String)
+	} else {
+		logger.SetRedactor(nil)
+	}
+}
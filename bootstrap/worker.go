@@ -0,0 +1,47 @@
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/utils/worker"
+)
+
+type workerPoolConfig struct {
+	Size           int           `mapstructure:"size"`
+	QueueSize      int           `mapstructure:"queueSize"`
+	Policy         string        `mapstructure:"policy"`
+	ExpiryDuration time.Duration `mapstructure:"expiryDuration"`
+}
+
+// configureWorkerPools creates the pools of worker.pools.<name>, names are lowercased by the config, e.g.
+//
+//	worker:
+//	  pools:
+//	    mail: {size: 16, queueSize: 256, policy: caller-runs}
+//
+// Get them with worker.Get("mail"), they are drained by ShutDown.
+func configureWorkerPools() {
+	var cfgs map[string]workerPoolConfig
+	if err := env.GetInstance().UnmarshalKey(env.WORKER_POOLS_KEY, &cfgs); err != nil {
+		logger.Error("[Bootstrap] worker.pools config error:", err.Error())
+		return
+	}
+	for name, c := range cfgs {
+		policy, err := worker.ParsePolicy(c.Policy)
+		if err != nil {
+			logger.Error("[Bootstrap] worker.pools."+name+" config error:", err.Error())
+			continue
+		}
+		if _, ok := worker.Get(name); ok {
+			continue
+		}
+		_, err = worker.NewPool(name, worker.Options{Size: c.Size, QueueSize: c.QueueSize, Policy: policy, ExpiryDuration: c.ExpiryDuration})
+		if err != nil {
+			logger.Error("[Bootstrap] create worker pool "+name+" error:", err.Error())
+			continue
+		}
+		logger.Info("[Bootstrap] worker pool created:", name, ",size:", c.Size, ",queueSize:", c.QueueSize, ",policy:", c.Policy)
+	}
+}
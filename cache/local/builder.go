@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/skirrund/gcloud/cache/local/internal/core"
+	"github.com/skirrund/gcloud/server/metrics"
 )
 
 const (
@@ -23,6 +24,7 @@ var (
 )
 
 type baseOptions[K comparable, V any] struct {
+	name             string
 	capacity         int
 	initialCapacity  int
 	statsEnabled     bool
@@ -35,6 +37,18 @@ func (o *baseOptions[K, V]) collectStats() {
 	o.statsEnabled = true
 }
 
+func (o *baseOptions[K, V]) setName(name string) {
+	o.name = name
+}
+
+// register exposes the statistics of a named cache to prometheus
+func (o *baseOptions[K, V]) register(c baseCache[K, V]) {
+	if !o.statsEnabled || len(o.name) == 0 {
+		return
+	}
+	metrics.RegisterCache(o.name, c.Size, func() metrics.CacheStats { return c.Stats() })
+}
+
 func (o *baseOptions[K, V]) setCostFunc(costFunc func(key K, value V) uint32) {
 	o.costFunc = costFunc
 	o.withCost = true
@@ -145,6 +159,14 @@ func (b *Builder[K, V]) CollectStats() *Builder[K, V] {
 	return b
 }
 
+// Name sets the name the statistics of the cache are exposed to prometheus under.
+//
+// The statistics are only exposed when CollectStats is set as well.
+func (b *Builder[K, V]) Name(name string) *Builder[K, V] {
+	b.setName(name)
+	return b
+}
+
 // InitialCapacity sets the minimum total size for the internal data structures. Providing a large enough estimate
 // at construction time avoids the need for expensive resizing operations later, but setting this
 // value unnecessarily high wastes memory.
@@ -199,7 +221,9 @@ func (b *Builder[K, V]) Build() (Cache[K, V], error) {
 		return Cache[K, V]{}, err
 	}
 
-	return newCache(b.toConfig()), nil
+	c := newCache(b.toConfig())
+	b.register(c.baseCache)
+	return c, nil
 }
 
 // ConstTTLBuilder is a one-shot builder for creating a cache instance.
@@ -215,6 +239,14 @@ func (b *ConstTTLBuilder[K, V]) CollectStats() *ConstTTLBuilder[K, V] {
 	return b
 }
 
+// Name sets the name the statistics of the cache are exposed to prometheus under.
+//
+// The statistics are only exposed when CollectStats is set as well.
+func (b *ConstTTLBuilder[K, V]) Name(name string) *ConstTTLBuilder[K, V] {
+	b.setName(name)
+	return b
+}
+
 // InitialCapacity sets the minimum total size for the internal data structures. Providing a large enough estimate
 // at construction time avoids the need for expensive resizing operations later, but setting this
 // value unnecessarily high wastes memory.
@@ -246,7 +278,9 @@ func (b *ConstTTLBuilder[K, V]) Build() (Cache[K, V], error) {
 		return Cache[K, V]{}, err
 	}
 
-	return newCache(b.toConfig()), nil
+	c := newCache(b.toConfig())
+	b.register(c.baseCache)
+	return c, nil
 }
 
 // VariableTTLBuilder is a one-shot builder for creating a cache instance.
@@ -262,6 +296,14 @@ func (b *VariableTTLBuilder[K, V]) CollectStats() *VariableTTLBuilder[K, V] {
 	return b
 }
 
+// Name sets the name the statistics of the cache are exposed to prometheus under.
+//
+// The statistics are only exposed when CollectStats is set as well.
+func (b *VariableTTLBuilder[K, V]) Name(name string) *VariableTTLBuilder[K, V] {
+	b.setName(name)
+	return b
+}
+
 // InitialCapacity sets the minimum total size for the internal data structures. Providing a large enough estimate
 // at construction time avoids the need for expensive resizing operations later, but setting this
 // value unnecessarily high wastes memory.
@@ -293,5 +335,7 @@ func (b *VariableTTLBuilder[K, V]) Build() (CacheWithVariableTTL[K, V], error) {
 		return CacheWithVariableTTL[K, V]{}, err
 	}
 
-	return newCacheWithVariableTTL(b.toConfig()), nil
+	c := newCacheWithVariableTTL(b.toConfig())
+	b.register(c.baseCache)
+	return c, nil
 }
@@ -0,0 +1,34 @@
+package local
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestBuilderName(t *testing.T) {
+	c, err := MustBuilder[string, int](10).Name("test").CollectStats().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("a", 1)
+	c.Get("a")
+	c.Get("b")
+	expected := `
+# HELP gcloud_cache_hits_total Cache hits.
+# TYPE gcloud_cache_hits_total counter
+gcloud_cache_hits_total{pool="test"} 1
+# HELP gcloud_cache_misses_total Cache misses.
+# TYPE gcloud_cache_misses_total counter
+gcloud_cache_misses_total{pool="test"} 1
+# HELP gcloud_cache_size Entries in the cache.
+# TYPE gcloud_cache_size gauge
+gcloud_cache_size{pool="test"} 1
+`
+	if err := testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(expected),
+		"gcloud_cache_hits_total", "gcloud_cache_misses_total", "gcloud_cache_size"); err != nil {
+		t.Error(err)
+	}
+}
@@ -4,11 +4,16 @@ import (
 	"context"
 	"crypto/tls"
 	"net"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/skirrund/gcloud/bootstrap/env"
 	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/plugins/otel"
+	"github.com/skirrund/gcloud/server/health"
+	"github.com/skirrund/gcloud/server/metrics"
 	"github.com/skirrund/gcloud/utils"
 
 	"github.com/redis/go-redis/v9"
@@ -66,6 +71,13 @@ type Options struct {
 	MasterName string `property:"redis.masterName"`
 }
 
+const HealthName = "redis"
+
+// poolName labels the pool metrics of a client with its addresses and db
+func (opts Options) poolName() string {
+	return strings.Join(opts.Addrs, ",") + "/" + strconv.Itoa(opts.DB)
+}
+
 var ctx = context.Background()
 
 var redisClient *RedisClient
@@ -116,6 +128,11 @@ func NewClient(opts Options) *RedisClient {
 			MasterName:       opts.MasterName,
 		})
 		redisClient.client = rdb
+		health.Register(HealthName, health.KindReadiness, redisClient.PingContext)
+		metrics.RegisterRedisPool(opts.poolName(), rdb)
+		if otel.Enabled() {
+			otel.InstrumentRedis(rdb)
+		}
 		err := redisClient.Ping()
 		if err != nil {
 			logger.Info("[redis] ping error:", err.Error())
@@ -135,7 +152,11 @@ func (r *RedisClient) Close() {
 }
 
 func (r *RedisClient) Ping() error {
-	sc := r.client.Ping(ctx)
+	return r.PingContext(ctx)
+}
+
+func (r *RedisClient) PingContext(c context.Context) error {
+	sc := r.client.Ping(c)
 	if sc.Err() != nil {
 		logger.Error("[redis] err", sc.Err().Error())
 		return sc.Err()
@@ -304,3 +325,8 @@ func (r *RedisClient) LPush(key string, valus ...any) int64 {
 	bc := r.client.LPush(ctx, key, valus...)
 	return bc.Val()
 }
+
+// Client exposes the underlying go-redis client for scripts and commands not wrapped here
+func (r *RedisClient) Client() redis.UniversalClient {
+	return r.client
+}
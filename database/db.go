@@ -4,12 +4,19 @@ import (
 	"context"
 	"errors"
 	"log"
+	"net/http"
 	"reflect"
 	"strings"
 	"time"
 
 	"github.com/skirrund/gcloud/bootstrap/env"
 	"github.com/skirrund/gcloud/database/option"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/plugins/otel"
+	"github.com/skirrund/gcloud/response"
+	"github.com/skirrund/gcloud/server"
+	"github.com/skirrund/gcloud/server/health"
+	"github.com/skirrund/gcloud/server/metrics"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -35,6 +42,7 @@ const (
 	DefaultMaxIdleConns    = 10
 	DefaultMaxOpenConns    = 50
 	DB_TYPE_MYSQL          = "mysql"
+	HealthName             = "db"
 )
 
 var db *gorm.DB
@@ -50,9 +58,23 @@ func InitDefault(dialector Dialector) {
 		QueryFields:     cfg.GetBool(DB_QueryFields),
 		Type:            cfg.GetStringWithDefault(DB_TYPE, DB_TYPE_MYSQL),
 	}, dialector)
+	health.Register(HealthName, health.KindReadiness, Ping)
 }
 func InitDefaultWithOption(option option.Option, dialector Dialector) {
 	db = InitDataSource(option, dialector)
+	health.Register(HealthName, health.KindReadiness, Ping)
+}
+
+// Ping checks the connection of the default datasource
+func Ping(ctx context.Context) error {
+	if db == nil {
+		return errors.New("db not initialized")
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
 }
 
 func doInit(option option.Option, dialector gorm.Dialector) *gorm.DB {
@@ -72,6 +94,16 @@ func doInit(option option.Option, dialector gorm.Dialector) *gorm.DB {
 	if err != nil {
 		log.Panicln(err)
 	} else {
+		name := option.Name
+		if len(name) == 0 {
+			name = option.Type
+		}
+		metrics.RegisterDB(name, sqlDB)
+		if otel.Enabled() {
+			if err := otel.InstrumentGorm(gormdb); err != nil {
+				logger.Error("[db] otel instrument error:", err.Error())
+			}
+		}
 		maxIdleConns := option.MaxIdleConns
 		if maxIdleConns == 0 {
 			sqlDB.SetMaxIdleConns(DefaultMaxIdleConns)
@@ -161,3 +193,7 @@ func Expr(expr string, args ...any) clause.Expr {
 func IsDuplicatedKeyError(err error) bool {
 	return errors.Is(err, gorm.ErrDuplicatedKey)
 }
+
+func init() {
+	server.RegisterError(gorm.ErrDuplicatedKey, server.ErrorMapping{Msginfo: response.DB_KEY_DUPLICATE, Status: http.StatusConflict, Internal: true})
+}
@@ -12,4 +12,6 @@ type Option struct {
 	QueryFields bool
 	//数据源类型：MySql
 	Type string
+	//Name labels the datasource in metrics, defaults to Type
+	Name string
 }
@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	// named maps logger names to the level overriding the base level for them and their children
+	named   atomic.Pointer[map[string]zapcore.Level]
+	namedMu sync.Mutex
+	// demoted are the logger names whose info entries are written at debug level
+	demoted atomic.Pointer[map[string]struct{}]
+)
+
+// ParseLevel parses debug, info, warn, error, dpanic, panic or fatal, case-insensitively
+func ParseLevel(text string) (zapcore.Level, error) {
+	return zapcore.ParseLevel(strings.ToLower(text))
+}
+
+// SetLevel changes the base level of every logger at runtime
+func SetLevel(lvl zapcore.Level) {
+	level.SetLevel(lvl)
+}
+
+// GetLevel returns the base level
+func GetLevel() zapcore.Level {
+	return level.Level()
+}
+
+// SetNamedLevel overrides the level of the logger called name, e.g. "cache.redis" from Named("cache").Named("redis"),
+// and of its children
+func SetNamedLevel(name string, lvl zapcore.Level) {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+	m := NamedLevels()
+	m[name] = lvl
+	named.Store(&m)
+}
+
+// UnsetNamedLevel removes the override of name, it follows its parent again
+func UnsetNamedLevel(name string) {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+	m := NamedLevels()
+	delete(m, name)
+	named.Store(&m)
+}
+
+// SetNamedLevels replaces all overrides
+func SetNamedLevels(levels map[string]zapcore.Level) {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+	m := maps.Clone(levels)
+	named.Store(&m)
+}
+
+// NamedLevels returns a copy of the overrides
+func NamedLevels() map[string]zapcore.Level {
+	m := make(map[string]zapcore.Level)
+	if p := named.Load(); p != nil {
+		maps.Copy(m, *p)
+	}
+	return m
+}
+
+// levelOf returns the level of the logger called name, the longest overridden prefix wins
+func levelOf(name string) zapcore.Level {
+	if p := named.Load(); p != nil {
+		if lvl, ok := lookupName(*p, name); ok {
+			return lvl
+		}
+	}
+	return level.Level()
+}
+
+// lookupName finds name or its longest dotted prefix in m
+func lookupName[V any](m map[string]V, name string) (V, bool) {
+	for n := name; len(n) > 0; {
+		if v, ok := m[n]; ok {
+			return v, true
+		}
+		i := strings.LastIndexByte(n, '.')
+		if i < 0 {
+			break
+		}
+		n = n[:i]
+	}
+	var zero V
+	return zero, false
+}
+
+// SetDemoted writes the info entries of the named loggers and their children at debug level,
+// e.g. SetDemoted("lb") turns the per request logs of the load balancer into debug logs
+func SetDemoted(names ...string) {
+	m := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		if len(n) > 0 {
+			m[n] = struct{}{}
+		}
+	}
+	demoted.Store(&m)
+}
+
+// Demoted returns the names set by SetDemoted
+func Demoted() []string {
+	p := demoted.Load()
+	if p == nil {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(*p))
+}
+
+func isDemoted(name string) bool {
+	p := demoted.Load()
+	if p == nil || len(*p) == 0 || len(name) == 0 {
+		return false
+	}
+	_, ok := lookupName(*p, name)
+	return ok
+}
+
+// minLevel is the lowest level any logger is enabled for
+func minLevel() zapcore.Level {
+	lvl := level.Level()
+	if p := named.Load(); p != nil {
+		for _, l := range *p {
+			lvl = min(lvl, l)
+		}
+	}
+	return lvl
+}
+
+// levelCore applies the base and named levels in front of the leaf cores
+type levelCore struct {
+	zapcore.Core
+}
+
+func (c levelCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= minLevel()
+}
+
+func (c levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return levelCore{c.Core.With(fields)}
+}
+
+func (c levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level == zapcore.InfoLevel && isDemoted(ent.LoggerName) {
+		ent.Level = zapcore.DebugLevel
+	}
+	if ent.Level < levelOf(ent.LoggerName) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+func (c levelCore) Level() zapcore.Level {
+	return minLevel()
+}
@@ -18,12 +18,7 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-type logger struct {
-	zapL  *zap.Logger
-	zapLS *zap.SugaredLogger
-}
-
-var defaultLogger *logger
+var defaultLogger *Logger
 
 var sLogger *slog.Logger
 
@@ -38,26 +33,20 @@ const (
 	DEFAULT_FILE = "log.log"
 )
 
-func Default() *logger {
+func Default() *Logger {
 	return defaultLogger
 }
 
 func init() {
 	slog.Info("[Logger] init default....")
 	encoder := getEncoder()
-	infoLevel := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return lvl > zapcore.DebugLevel
-	})
 	c := zapcore.AddSync(os.Stderr)
 	core := zapcore.NewTee(
-		zapcore.NewCore(encoder, c, infoLevel),
+		newCore(encoder, c, zapcore.DebugLevel),
+		newSinkCore(),
 	)
-	zapL := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
-	zapLS := zapL.Sugar()
-	defaultLogger = &logger{
-		zapL:  zapL,
-		zapLS: zapLS,
-	}
+	zapL := zap.New(levelCore{throttleCore{core}}, zap.AddCaller(), zap.AddCallerSkip(1))
+	defaultLogger = newLogger(zapL)
 	sLogger = slog.New(zapslog.NewHandler(zapL.Core(), zapslog.WithCaller(true)))
 	slog.SetDefault(sLogger)
 }
@@ -164,6 +153,26 @@ func getArgs(ctx context.Context, args ...any) []any {
 	}
 }
 
+func DebugContext(ctx context.Context, args ...any) {
+	args = getArgs(ctx, args...)
+	Default().zapLS.Debug(args...)
+}
+
+func Debug(args ...any) {
+	args = getArgs(defaultCtx, args...)
+	Default().zapLS.Debug(args...)
+}
+
+func DebugfContext(ctx context.Context, template string, args ...any) {
+	args = getArgs(ctx, args...)
+	Default().zapLS.Debugf("%s%s"+template, args...)
+}
+
+func Debugf(template string, args ...any) {
+	args = getArgs(defaultCtx, args...)
+	Default().zapLS.Debugf("%s%s"+template, args...)
+}
+
 func InfoContext(ctx context.Context, args ...any) {
 	args = getArgs(ctx, args...)
 	Default().zapLS.Info(args...)
@@ -221,17 +230,15 @@ func getJSONEncoder(service string) zapcore.Encoder {
 	return encoder
 }
 
-func initLog(fileDir string, serviceName string, port string, console bool, json bool, maxAge time.Duration) *zap.Logger {
+func initLog(fileDir string, serviceName string, port string, console bool, json bool, maxAge time.Duration, r Rotation) *zap.Logger {
 	encoder := getEncoder()
 	jsonEncoder := getJSONEncoder(serviceName)
-	// 实现两个判断日志等级的interface (其实 zapcore.*Level 自身就是 interface)
-	infoLevel := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return lvl > zapcore.DebugLevel
-	})
+	// the leaf cores take every level, levelCore applies SetLevel and SetNamedLevel in front of them
+	allLevels := zapcore.DebugLevel
 	// 获取 info、warn日志文件的io.Writer 抽象 getWriter() 在下方实现
-	infoWriter := getWriter(fileDir, serviceName, port, maxAge)
+	infoWriter := getWriter(fileDir, serviceName, port, maxAge, r)
 	//	warnWriter := getWriter("log/log.log")
-	jsonWriter := getWriterJSON(fileDir, serviceName, port)
+	jsonWriter := getWriterJSON(fileDir, serviceName, port, r)
 	jWriter := zapcore.AddSync(jsonWriter)
 	writer := zapcore.AddSync(infoWriter)
 	var core zapcore.Core
@@ -239,30 +246,31 @@ func initLog(fileDir string, serviceName string, port string, console bool, json
 		c := zapcore.AddSync(os.Stdout)
 		if json {
 			core = zapcore.NewTee(
-				zapcore.NewCore(encoder, writer, infoLevel),
-				zapcore.NewCore(encoder, c, infoLevel),
-				zapcore.NewCore(jsonEncoder, jWriter, infoLevel),
+				newCore(encoder, writer, allLevels),
+				newCore(encoder, c, allLevels),
+				newCore(jsonEncoder, jWriter, allLevels),
 			)
 		} else {
 			core = zapcore.NewTee(
-				zapcore.NewCore(encoder, writer, infoLevel),
-				zapcore.NewCore(encoder, c, infoLevel),
+				newCore(encoder, writer, allLevels),
+				newCore(encoder, c, allLevels),
 			)
 		}
 	} else {
 		if json {
 			core = zapcore.NewTee(
-				zapcore.NewCore(encoder, writer, infoLevel),
-				zapcore.NewCore(jsonEncoder, jWriter, infoLevel),
+				newCore(encoder, writer, allLevels),
+				newCore(jsonEncoder, jWriter, allLevels),
 			)
 		} else {
 			core = zapcore.NewTee(
-				zapcore.NewCore(encoder, writer, infoLevel),
+				newCore(encoder, writer, allLevels),
 			)
 		}
 	}
+	core = zapcore.NewTee(core, newSinkCore())
 	//core = core.With([]zapcore.Field{zapcore.Field{Key: "service", Type: zapcore.StringType, String: service}})
-	return zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
+	return zap.New(levelCore{throttleCore{core}}, zap.AddCaller(), zap.AddCallerSkip(1))
 }
 
 func NewLogInstance(fileDir string, serviceName string, port string, console bool, json bool, maxAgeDay uint64) *slog.Logger {
@@ -270,22 +278,24 @@ func NewLogInstance(fileDir string, serviceName string, port string, console boo
 	if maxAgeDay == 0 {
 		maxAgeDay = 7
 	}
-	z := initLog(fileDir, serviceName, port, console, json, time.Duration(maxAgeDay)*time.Hour*24)
+	z := initLog(fileDir, serviceName, port, console, json, time.Duration(maxAgeDay)*time.Hour*24, Rotation{})
 	logger = slog.New(zapslog.NewHandler(z.Core(), zapslog.WithCaller(true)))
 	return logger
 }
 
 func InitLog(fileDir string, serviceName string, port string, console bool, json bool, maxAgeDay uint64) {
+	InitLogWithRotation(fileDir, serviceName, port, console, json, maxAgeDay, Rotation{})
+}
+
+// InitLogWithRotation is InitLog with size based rotation, compression and a disk quota
+func InitLogWithRotation(fileDir string, serviceName string, port string, console bool, json bool, maxAgeDay uint64, r Rotation) {
 	once.Do(func() {
 		if maxAgeDay == 0 {
 			maxAgeDay = 7
 		}
-		zapL := initLog(fileDir, serviceName, port, console, json, time.Duration(maxAgeDay)*time.Hour*24)
-		zapLS := zapL.Sugar()
-		defaultLogger = &logger{
-			zapL:  zapL,
-			zapLS: zapLS,
-		}
+		zapL := initLog(fileDir, serviceName, port, console, json, time.Duration(maxAgeDay)*time.Hour*24, r)
+		setSinkEncoder(getJSONEncoder(serviceName))
+		defaultLogger = newLogger(zapL)
 		sLogger = slog.New(zapslog.NewHandler(zapL.Core(), zapslog.WithCaller(true)))
 		slog.SetDefault(sLogger)
 	})
@@ -296,7 +306,7 @@ func getFileName(serviceName string, port string) string {
 	return "/" + serviceName + "/" + host + "-" + port // ".log.%Y-%m-%d"
 }
 
-func getWriter(fileDir string, serviceName string, port string, maxAgeDay time.Duration) io.Writer {
+func getWriter(fileDir string, serviceName string, port string, maxAgeDay time.Duration, r Rotation) io.Writer {
 	// 生成rotatelogs的Logger 实际生成的文件名 demo.log.YYmmddHH
 	// demo.log是指向最新日志的链接
 	// 保存7天内的日志，每1小时(整点)分割一次日志
@@ -307,13 +317,14 @@ func getWriter(fileDir string, serviceName string, port string, maxAgeDay time.D
 	hook, err := rotatelogs.New(
 		p, // 没有使用go风格反人类的format格式%Y-%m-%d-%H
 		//rotatelogs.WithLinkName(fileDir+fileName+".log"),
-		rotatelogs.WithMaxAge(maxAgeDay),
-		rotatelogs.WithRotationTime(time.Hour),
-		rotatelogs.WithHandler(rotatelogs.HandlerFunc(func(e rotatelogs.Event) {
-			if e.Type() != rotatelogs.FileRotatedEventType {
-				return
-			}
-		})),
+		append(r.options(),
+			rotatelogs.WithMaxAge(maxAgeDay),
+			rotatelogs.WithHandler(rotatelogs.HandlerFunc(func(e rotatelogs.Event) {
+				if e.Type() != rotatelogs.FileRotatedEventType {
+					return
+				}
+			})),
+		)...,
 	)
 
 	if err != nil {
@@ -322,7 +333,7 @@ func getWriter(fileDir string, serviceName string, port string, maxAgeDay time.D
 	return hook
 }
 
-func getWriterJSON(fileDir string, serviceName string, port string) io.Writer {
+func getWriterJSON(fileDir string, serviceName string, port string, r Rotation) io.Writer {
 	// 生成rotatelogs的Logger 实际生成的文件名 demo.log.YYmmddHH
 	// demo.log是指向最新日志的链接
 	// 保存7天内的日志，每1小时(整点)分割一次日志
@@ -333,13 +344,14 @@ func getWriterJSON(fileDir string, serviceName string, port string) io.Writer {
 	hook, err := rotatelogs.New(
 		p, // 没有使用go风格反人类的format格式%Y-%m-%d-%H
 		//rotatelogs.WithLinkName(fileDir+fileName+".json"),
-		rotatelogs.WithMaxAge(time.Hour*24*3),
-		rotatelogs.WithRotationTime(time.Hour),
-		rotatelogs.WithHandler(rotatelogs.HandlerFunc(func(e rotatelogs.Event) {
-			if e.Type() != rotatelogs.FileRotatedEventType {
-				return
-			}
-		})),
+		append(r.options(),
+			rotatelogs.WithMaxAge(time.Hour*24*3),
+			rotatelogs.WithHandler(rotatelogs.HandlerFunc(func(e rotatelogs.Event) {
+				if e.Type() != rotatelogs.FileRotatedEventType {
+					return
+				}
+			})),
+		)...,
 	)
 
 	if err != nil {
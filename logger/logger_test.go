@@ -2,14 +2,18 @@ package logger
 
 import (
 	"context"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/skirrund/gcloud/tracer"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestLogger(t *testing.T) {
-	initLog("logger", "test", "111", true, false, 1*time.Hour)
+	initLog("logger", "test", "111", true, false, 1*time.Hour, Rotation{})
 	ctx := context.Background()
 	InfofContext(tracer.NewTraceIDContext(), "info.......%s%s%s", "1", "-", "2")
 	Info("warn1.....")
@@ -18,3 +22,85 @@ func TestLogger(t *testing.T) {
 	WarnfContext(tracer.WithTraceID(ctx), "info.......%s", "2")
 	Warnf("info.......%s", "2")
 }
+
+func TestStructuredLevels(t *testing.T) {
+	// created before the default logger is replaced, like package variables
+	child := Named("cache").With("pool", "main")
+	core, logs := observer.New(zapcore.DebugLevel)
+	old := defaultLogger
+	defaultLogger = newLogger(zap.New(levelCore{core}))
+	t.Cleanup(func() {
+		defaultLogger = old
+		SetLevel(zapcore.InfoLevel)
+		SetNamedLevels(nil)
+	})
+
+	SetLevel(zapcore.InfoLevel)
+	child.Debug("hidden")
+	child.Named("redis").Info("shown", "key", "k1")
+	SetNamedLevel("cache", zapcore.DebugLevel)
+	child.Named("redis").Debug("override")
+	Named("cachex").Debug("prefix is not a parent")
+	Debug("base still info")
+	SetNamedLevel("cache.redis", zapcore.ErrorLevel)
+	child.Named("redis").Warn("longest override wins")
+	child.InfoContext(tracer.NewContextFromTraceId("t1"), "traced")
+
+	var msgs []string
+	for _, e := range logs.All() {
+		msgs = append(msgs, e.Message)
+	}
+	want := []string{"shown", "override", "traced"}
+	if !slices.Equal(msgs, want) {
+		t.Fatalf("logged %q, want %q", msgs, want)
+	}
+	first := logs.All()[0]
+	if first.LoggerName != "cache.redis" || first.ContextMap()["pool"] != "main" || first.ContextMap()["key"] != "k1" {
+		t.Fatalf("unexpected entry %+v", first)
+	}
+	if id := logs.All()[2].ContextMap()[TraceIDField]; id != "t1" {
+		t.Fatalf("trace id %v", id)
+	}
+}
+
+func TestThrottling(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	old := defaultLogger
+	defaultLogger = newLogger(zap.New(levelCore{throttleCore{core}}))
+	t.Cleanup(func() {
+		defaultLogger = old
+		SetLevel(zapcore.InfoLevel)
+		SetDemoted()
+		SetSampling(nil)
+		SetRateLimits(nil)
+	})
+
+	SetDemoted("lb")
+	Named("lb").Named("client").Info("demoted")
+	SetLevel(zapcore.DebugLevel)
+	Named("lb").Info("demoted but shown")
+	SetLevel(zapcore.InfoLevel)
+	if all := logs.TakeAll(); len(all) != 1 || all[0].Level != zapcore.DebugLevel {
+		t.Fatalf("demoted entries %+v", all)
+	}
+
+	SetRateLimits([]RateLimit{{Key: "[LB] get", Rate: 0, Burst: 2}})
+	for i := 0; i < 5; i++ {
+		Named("x").Info("[LB] get instance", "i", i)
+	}
+	if n := len(logs.TakeAll()); n != 2 {
+		t.Fatalf("rate limited to %d entries", n)
+	}
+
+	SetRateLimits(nil)
+	SetSampling(&Sampling{Tick: time.Minute, First: 2, Thereafter: 3})
+	for i := 0; i < 8; i++ {
+		Info("sampled")
+	}
+	if n := len(logs.TakeAll()); n != 4 {
+		t.Fatalf("sampled %d entries, want 4", n)
+	}
+	if sampled, limited := Throttled(); sampled < 4 || limited < 3 {
+		t.Fatalf("throttled %d sampled, %d limited", sampled, limited)
+	}
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var redactor atomic.Pointer[func(string) string]
+
+// SetRedactor masks every message and string field before it is written, nil turns it off.
+// The logger can't depend on utils, so the redaction engine is plugged in from outside.
+func SetRedactor(f func(string) string) {
+	if f == nil {
+		redactor.Store(nil)
+		return
+	}
+	redactor.Store(&f)
+}
+
+// redactCore wraps a leaf core and runs the redactor over entries written to it
+type redactCore struct {
+	zapcore.Core
+}
+
+func newCore(enc zapcore.Encoder, ws zapcore.WriteSyncer, enab zapcore.LevelEnabler) zapcore.Core {
+	return redactCore{zapcore.NewCore(enc, ws, enab)}
+}
+
+func (c redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return redactCore{c.Core.With(redactFields(fields))}
+}
+
+func (c redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if f := redactor.Load(); f != nil {
+		ent.Message = (*f)(ent.Message)
+		fields = redactFields(fields)
+	}
+	return c.Core.Write(ent, fields)
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	f := redactor.Load()
+	if f == nil {
+		return fields
+	}
+	var out []zapcore.Field
+	for i, field := range fields {
+		if field.Type != zapcore.StringType {
+			continue
+		}
+		if s := (*f)(field.String); s != field.String {
+			if out == nil {
+				out = append(make([]zapcore.Field, 0, len(fields)), fields...)
+			}
+			out[i].String = s
+		}
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
@@ -0,0 +1,221 @@
+package rotatelogs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/skirrund/gcloud/utils/gerrors"
+)
+
+var compressedExt = map[string]string{
+	CompressionGzip: ".gz",
+	CompressionZstd: ".zst",
+}
+
+// matches lists the files of the pattern in lexical order, including
+// generations such as "foo.1" and compressed files
+func (rl *RotateLogs) matches() ([]string, error) {
+	seen := make(map[string]struct{})
+	var out []string
+	for _, glob := range []string{rl.globPattern, rl.globPattern + ".*"} {
+		m, err := filepath.Glob(glob)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range m {
+			if _, ok := seen[path]; !ok {
+				seen[path] = struct{}{}
+				out = append(out, path)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// exists reports whether name or a compressed copy of it exists
+func exists(name string) bool {
+	if _, err := os.Stat(name); err == nil {
+		return true
+	}
+	for _, ext := range compressedExt {
+		if _, err := os.Stat(name + ext); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ignored are the lock, symlink and temporary files of rotation and compression
+func ignored(path string) bool {
+	return strings.HasSuffix(path, "_lock") || strings.HasSuffix(path, "_symlink") || strings.HasSuffix(path, "_tmp")
+}
+
+func compressed(path string) bool {
+	for _, ext := range compressedExt {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// maintainNolock wakes the background pass that compresses the rotated
+// files and enforces the quota. A wake-up during a pass runs it once more.
+func (rl *RotateLogs) maintainNolock() {
+	if rl.compression == CompressionNone && rl.maxTotalSize <= 0 {
+		return
+	}
+	if rl.wake == nil {
+		rl.wake = make(chan struct{}, 1)
+		go rl.maintainLoop(rl.wake)
+	}
+	select {
+	case rl.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (rl *RotateLogs) maintainLoop(wake chan struct{}) {
+	for range wake {
+		if err := rl.maintain(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		}
+	}
+}
+
+// files globs the files of the pattern and reads the current one under the same lock,
+// a rotation in between would make the new current file look like a rotated one
+func (rl *RotateLogs) files() (string, []string, error) {
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+	matches, err := rl.matches()
+	return rl.curFn, matches, err
+}
+
+func (rl *RotateLogs) maintain() error {
+	var errs []error
+	if rl.compression != CompressionNone {
+		current, matches, err := rl.files()
+		if err != nil {
+			return err
+		}
+		for _, path := range matches {
+			if path == current || ignored(path) || compressed(path) {
+				continue
+			}
+			if err := compressFile(path, rl.compression); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if rl.maxTotalSize > 0 {
+		errs = append(errs, rl.enforceQuota())
+	}
+	return errors.Join(errs...)
+}
+
+type logFile struct {
+	path string
+	size int64
+	mod  time.Time
+}
+
+// enforceQuota removes the oldest files until the files of the pattern fit into maxTotalSize
+func (rl *RotateLogs) enforceQuota() error {
+	current, matches, err := rl.files()
+	if err != nil {
+		return err
+	}
+	var files []logFile
+	var total int64
+	for _, path := range matches {
+		if ignored(path) {
+			continue
+		}
+		fi, err := os.Lstat(path)
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		total += fi.Size()
+		if path != current {
+			files = append(files, logFile{path: path, size: fi.Size(), mod: fi.ModTime()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].mod.Before(files[j].mod) })
+	for _, f := range files {
+		if total <= rl.maxTotalSize {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return gerrors.Wrapf(err, "failed to remove %s", f.path)
+		}
+		total -= f.size
+	}
+	return nil
+}
+
+// compressFile replaces path with a compressed copy that keeps its modification time
+func compressFile(path string, compression string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return gerrors.Wrapf(err, "failed to open %s", path)
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	ext := compressedExt[compression]
+	target := path + ext
+	// a file recreated under a name that was compressed before must not overwrite the old copy
+	for i := 1; exists(target); i++ {
+		target = path + "." + strconv.Itoa(i) + ext
+	}
+	tmp := target + "_tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return gerrors.Wrapf(err, "failed to create %s", tmp)
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(tmp)
+		}
+	}()
+	var w io.WriteCloser
+	switch compression {
+	case CompressionZstd:
+		if w, err = zstd.NewWriter(dst); err != nil {
+			return err
+		}
+	default:
+		w = gzip.NewWriter(dst)
+	}
+	if _, err = io.Copy(w, src); err != nil {
+		w.Close()
+		return gerrors.Wrapf(err, "failed to compress %s", path)
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	if err = os.Chtimes(tmp, fi.ModTime(), fi.ModTime()); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, target); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
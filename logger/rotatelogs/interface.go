@@ -48,6 +48,10 @@ type RotateLogs struct {
 	rotationSize  int64
 	rotationCount uint
 	forceNewFile  bool
+	compression   string
+	maxTotalSize  int64
+	// wake triggers the background compression and quota pass
+	wake chan struct{}
 }
 
 // Clock is the interface used by the RotateLogs
@@ -13,6 +13,14 @@ const (
 	optkeyRotationSize  = "rotation-size"
 	optkeyRotationCount = "rotation-count"
 	optkeyForceNewFile  = "force-new-file"
+	optkeyCompression   = "compression"
+	optkeyMaxTotalSize  = "max-total-size"
+)
+
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
 )
 
 // WithClock creates a new Option that sets a clock
@@ -85,3 +93,18 @@ func WithHandler(h Handler) Option {
 func ForceNewFile() Option {
 	return *NewOption(optkeyForceNewFile, true)
 }
+
+// WithCompression creates a new Option that compresses rotated
+// files in the background with CompressionGzip (".gz") or
+// CompressionZstd (".zst"). The modification time is kept, so
+// MaxAge still counts from the last write.
+func WithCompression(c string) Option {
+	return *NewOption(optkeyCompression, c)
+}
+
+// WithMaxTotalSize creates a new Option that removes the oldest
+// rotated files once all files matching the pattern take more
+// than n bytes. The current file is never removed.
+func WithMaxTotalSize(n int64) Option {
+	return *NewOption(optkeyMaxTotalSize, n)
+}
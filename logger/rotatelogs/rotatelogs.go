@@ -39,6 +39,8 @@ func New(p string, options ...Option) (*RotateLogs, error) {
 	var maxAge time.Duration
 	var handler Handler
 	var forceNewFile bool
+	var compression string
+	var maxTotalSize int64
 
 	for _, o := range options {
 		switch o.Name() {
@@ -67,9 +69,22 @@ func New(p string, options ...Option) (*RotateLogs, error) {
 			handler = o.Value().(Handler)
 		case optkeyForceNewFile:
 			forceNewFile = true
+		case optkeyCompression:
+			compression = o.Value().(string)
+		case optkeyMaxTotalSize:
+			maxTotalSize = o.Value().(int64)
+			if maxTotalSize < 0 {
+				maxTotalSize = 0
+			}
 		}
 	}
 
+	switch compression {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return nil, gerrors.Errorf("unknown compression %s", compression)
+	}
+
 	if maxAge > 0 && rotationCount > 0 {
 		return nil, gerrors.New("options MaxAge and RotationCount cannot be both set")
 	}
@@ -90,6 +105,8 @@ func New(p string, options ...Option) (*RotateLogs, error) {
 		rotationSize:  rotationSize,
 		rotationCount: rotationCount,
 		forceNewFile:  forceNewFile,
+		compression:   compression,
+		maxTotalSize:  maxTotalSize,
 	}, nil
 }
 
@@ -154,7 +171,7 @@ func (rl *RotateLogs) getWriterNolock(bailOnRotateFail, useGenerationalNames boo
 			} else {
 				name = fmt.Sprintf("%s.%d", filename, generation)
 			}
-			if _, err := os.Stat(name); err != nil {
+			if !exists(name) {
 				filename = name
 
 				break
@@ -192,6 +209,7 @@ func (rl *RotateLogs) getWriterNolock(bailOnRotateFail, useGenerationalNames boo
 	rl.curBaseFn = baseFn
 	rl.curFn = filename
 	rl.generation = generation
+	rl.maintainNolock()
 	if h := rl.eventHandler; h != nil {
 		go h.Handle(&FileRotatedEvent{
 			prev:    previousFn,
@@ -303,7 +321,7 @@ func (rl *RotateLogs) rotateNolock(filename string) error {
 		return gerrors.New("panic: maxAge and rotationCount are both set")
 	}
 
-	matches, err := filepath.Glob(rl.globPattern)
+	matches, err := rl.matches()
 	if err != nil {
 		return err
 	}
@@ -314,7 +332,7 @@ func (rl *RotateLogs) rotateNolock(filename string) error {
 	toUnlink := make([]string, 0, len(matches))
 	for _, path := range matches {
 		// Ignore lock files
-		if strings.HasSuffix(path, "_lock") || strings.HasSuffix(path, "_symlink") {
+		if ignored(path) {
 			continue
 		}
 
@@ -375,6 +393,10 @@ func (rl *RotateLogs) Close() error {
 
 	rl.outFh.Close()
 	rl.outFh = nil
+	if rl.wake != nil {
+		close(rl.wake)
+		rl.wake = nil
+	}
 
 	return nil
 }
@@ -0,0 +1,60 @@
+package rotatelogs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSizeRotationCompression(t *testing.T) {
+	dir := t.TempDir()
+	rl, err := New(filepath.Join(dir, "app.log.%Y-%m-%d"),
+		WithRotationSize(64),
+		WithCompression(CompressionGzip),
+		WithMaxTotalSize(400),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+	line := []byte(strings.Repeat("x", 63) + "\n")
+	for i := 0; i < 20; i++ {
+		if _, err := rl.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var gz int
+	var total int64
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		matches, _ := rl.matches()
+		gz, total = 0, 0
+		plain := 0
+		for _, m := range matches {
+			fi, err := os.Stat(m)
+			if err != nil {
+				continue
+			}
+			total += fi.Size()
+			if strings.HasSuffix(m, ".gz") {
+				gz++
+			} else {
+				plain++
+			}
+		}
+		if plain == 1 && gz > 0 && total <= 400 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("rotated files not compressed or over quota: %d compressed, %d bytes", gz, total)
+}
+
+func TestInvalidCompression(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "app.log.%Y"), WithCompression("lz4")); err == nil {
+		t.Fatal("expected an error for an unknown compression")
+	}
+}
@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/skirrund/gcloud/logger/rotatelogs"
+)
+
+// Rotation controls how the log files are rotated, the zero value rotates hourly
+type Rotation struct {
+	// Time between two rotations, one hour when zero
+	Time time.Duration
+	// MaxSize rotates the file once it reaches that many bytes, 0 disables size rotation
+	MaxSize int64
+	// Compress the rotated files with rotatelogs.CompressionGzip or rotatelogs.CompressionZstd
+	Compress string
+	// MaxTotalSize removes the oldest files once all files together exceed that many bytes
+	MaxTotalSize int64
+}
+
+func (r Rotation) options() []rotatelogs.Option {
+	t := r.Time
+	if t <= 0 {
+		t = time.Hour
+	}
+	return []rotatelogs.Option{
+		rotatelogs.WithRotationTime(t),
+		rotatelogs.WithRotationSize(r.MaxSize),
+		rotatelogs.WithCompression(r.Compress),
+		rotatelogs.WithMaxTotalSize(r.MaxTotalSize),
+	}
+}
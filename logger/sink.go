@@ -0,0 +1,378 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Record is one log entry handed to a Sink
+type Record struct {
+	Entry zapcore.Entry
+	// Line is the entry with its fields encoded as JSON, without the trailing newline
+	Line []byte
+}
+
+// Sink ships batches of records somewhere other than the local files.
+// Write is only called from the goroutine of the sink and must not retain records after it returns.
+type Sink interface {
+	Write(ctx context.Context, records []Record) error
+	Close() error
+}
+
+// RetryableError is returned by Write when the batch may be accepted later, e.g. on a 429 or 5xx.
+// After is the wait the receiver asked for, zero leaves it to the backoff.
+type RetryableError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+const (
+	DefaultSinkQueueSize     = 4096
+	DefaultSinkBatchSize     = 256
+	DefaultSinkFlushInterval = time.Second
+	DefaultSinkTimeout       = 10 * time.Second
+	DefaultSinkMaxRetries    = 3
+	DefaultSinkRetryBackoff  = 500 * time.Millisecond
+	// MaxSinkRetryWait bounds the backoff and the Retry-After of the receiver
+	MaxSinkRetryWait = 30 * time.Second
+)
+
+type SinkOptions struct {
+	// Level is the lowest level shipped, on top of SetLevel and SetNamedLevel
+	Level zapcore.Level
+	// QueueSize bounds the records waiting to be shipped
+	QueueSize int
+	// BatchSize is the most records handed to one Write
+	BatchSize int
+	// FlushInterval ships an incomplete batch
+	FlushInterval time.Duration
+	// Timeout of one Write
+	Timeout time.Duration
+	// Block makes the logging goroutine wait for room in a full queue instead of dropping the record
+	Block bool
+	// MaxRetries of a batch failing with a RetryableError, DefaultSinkMaxRetries when 0, negative disables retries.
+	// The queue keeps filling while a batch is retried.
+	MaxRetries int
+	// RetryBackoff is the first wait between retries, it doubles with every retry
+	RetryBackoff time.Duration
+}
+
+// SinkStats are the counters of one sink, Dropped counts records that did not fit into
+// the queue and Failed the records of batches Write still returned an error for after the retries
+type SinkStats struct {
+	Name    string `json:"name"`
+	Level   string `json:"level"`
+	Queued  int    `json:"queued"`
+	Shipped uint64 `json:"shipped"`
+	Dropped uint64 `json:"dropped"`
+	Failed  uint64 `json:"failed"`
+}
+
+type asyncSink struct {
+	name  string
+	sink  Sink
+	opts  SinkOptions
+	queue chan Record
+	stop  chan struct{}
+	done  chan struct{}
+
+	shipped atomic.Uint64
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+}
+
+var (
+	sinksMu sync.Mutex
+	sinks   atomic.Pointer[[]*asyncSink]
+	// sinkEncoder encodes Record.Line, InitLog replaces it to add the service name
+	sinkEncoder atomic.Pointer[zapcore.Encoder]
+)
+
+func init() {
+	setSinkEncoder(getJSONEncoder(""))
+}
+
+func setSinkEncoder(enc zapcore.Encoder) {
+	sinkEncoder.Store(&enc)
+}
+
+// AddSink starts shipping the entries of every logger to s under name
+func AddSink(name string, s Sink, opts SinkOptions) error {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultSinkQueueSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultSinkBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultSinkFlushInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultSinkTimeout
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = DefaultSinkMaxRetries
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = DefaultSinkRetryBackoff
+	}
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	cur := currentSinks()
+	for _, as := range cur {
+		if as.name == name {
+			return fmt.Errorf("logger: sink %s already added", name)
+		}
+	}
+	as := &asyncSink{
+		name:  name,
+		sink:  s,
+		opts:  opts,
+		queue: make(chan Record, opts.QueueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go as.run()
+	next := append(slices.Clip(cur), as)
+	sinks.Store(&next)
+	return nil
+}
+
+// RemoveSink stops shipping to the sink, ships what is queued and closes it
+func RemoveSink(name string) error {
+	sinksMu.Lock()
+	cur := currentSinks()
+	i := slices.IndexFunc(cur, func(as *asyncSink) bool { return as.name == name })
+	if i < 0 {
+		sinksMu.Unlock()
+		return fmt.Errorf("logger: sink %s not found", name)
+	}
+	as := cur[i]
+	next := slices.Delete(slices.Clone(cur), i, i+1)
+	sinks.Store(&next)
+	sinksMu.Unlock()
+	return as.close()
+}
+
+// CloseSinks ships what is queued and closes every sink, it is called on shutdown
+func CloseSinks() error {
+	sinksMu.Lock()
+	cur := currentSinks()
+	sinks.Store(nil)
+	sinksMu.Unlock()
+	var errs []error
+	for _, as := range cur {
+		errs = append(errs, as.close())
+	}
+	return errors.Join(errs...)
+}
+
+// LookupSink returns the sink added under name, e.g. to read a *RingSink
+func LookupSink(name string) (Sink, bool) {
+	for _, as := range currentSinks() {
+		if as.name == name {
+			return as.sink, true
+		}
+	}
+	return nil, false
+}
+
+// Sinks returns the counters of every sink ordered by name
+func Sinks() []SinkStats {
+	cur := currentSinks()
+	stats := make([]SinkStats, 0, len(cur))
+	for _, as := range cur {
+		stats = append(stats, SinkStats{
+			Name:    as.name,
+			Level:   as.opts.Level.String(),
+			Queued:  len(as.queue),
+			Shipped: as.shipped.Load(),
+			Dropped: as.dropped.Load(),
+			Failed:  as.failed.Load(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+func currentSinks() []*asyncSink {
+	if p := sinks.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+func (as *asyncSink) enqueue(r Record) {
+	select {
+	case <-as.stop:
+		as.dropped.Add(1)
+		return
+	default:
+	}
+	if as.opts.Block {
+		select {
+		case as.queue <- r:
+		case <-as.stop:
+			as.dropped.Add(1)
+		}
+		return
+	}
+	select {
+	case as.queue <- r:
+	default:
+		as.dropped.Add(1)
+	}
+}
+
+func (as *asyncSink) run() {
+	defer close(as.done)
+	ticker := time.NewTicker(as.opts.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]Record, 0, as.opts.BatchSize)
+	add := func(r Record) {
+		batch = append(batch, r)
+		if len(batch) >= as.opts.BatchSize {
+			batch = as.ship(batch)
+		}
+	}
+	for {
+		select {
+		case r := <-as.queue:
+			add(r)
+		case <-ticker.C:
+			batch = as.ship(batch)
+		case <-as.stop:
+			for {
+				select {
+				case r := <-as.queue:
+					add(r)
+				default:
+					as.ship(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (as *asyncSink) ship(batch []Record) []Record {
+	if len(batch) == 0 {
+		return batch
+	}
+	err := as.write(batch)
+	for retry := 0; err != nil && retry < as.opts.MaxRetries; retry++ {
+		var re *RetryableError
+		if !errors.As(err, &re) {
+			break
+		}
+		wait := min(max(as.opts.RetryBackoff<<retry, re.After), MaxSinkRetryWait)
+		if !as.sleep(wait) {
+			break
+		}
+		err = as.write(batch)
+	}
+	if err != nil {
+		as.failed.Add(uint64(len(batch)))
+		// logging the failure would feed it back into the sink
+		fmt.Fprintf(os.Stderr, "[logger] sink %s error: %s\n", as.name, err.Error())
+	} else {
+		as.shipped.Add(uint64(len(batch)))
+	}
+	clear(batch)
+	return batch[:0]
+}
+
+func (as *asyncSink) write(batch []Record) error {
+	ctx, cancel := context.WithTimeout(context.Background(), as.opts.Timeout)
+	defer cancel()
+	return as.sink.Write(ctx, batch)
+}
+
+// sleep waits between retries, it gives up when the sink is closed so shutdown is not held by an outage
+func (as *asyncSink) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-as.stop:
+		return false
+	}
+}
+
+func (as *asyncSink) close() error {
+	close(as.stop)
+	<-as.done
+	return as.sink.Close()
+}
+
+// sinkCore hands the entries to the sinks whose level they pass, it is wrapped like the file
+// cores so the sinks see redacted entries
+type sinkCore struct {
+	fields []zapcore.Field
+}
+
+func newSinkCore() zapcore.Core {
+	return redactCore{sinkCore{}}
+}
+
+func (c sinkCore) Enabled(lvl zapcore.Level) bool {
+	for _, as := range currentSinks() {
+		if as.opts.Level.Enabled(lvl) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c sinkCore) With(fields []zapcore.Field) zapcore.Core {
+	return sinkCore{fields: append(slices.Clip(c.fields), fields...)}
+}
+
+func (c sinkCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c sinkCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var line []byte
+	for _, as := range currentSinks() {
+		if !as.opts.Level.Enabled(ent.Level) {
+			continue
+		}
+		if line == nil {
+			buf, err := (*sinkEncoder.Load()).EncodeEntry(ent, append(slices.Clip(c.fields), fields...))
+			if err != nil {
+				return err
+			}
+			line = bytes.Clone(bytes.TrimRight(buf.Bytes(), "\n"))
+			buf.Free()
+		}
+		as.enqueue(Record{Entry: ent, Line: line})
+	}
+	return nil
+}
+
+func (c sinkCore) Sync() error {
+	return nil
+}
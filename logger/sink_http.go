@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HTTPSinkJSON          = "json"
+	HTTPSinkLoki          = "loki"
+	HTTPSinkElasticsearch = "elasticsearch"
+)
+
+type HTTPSinkOptions struct {
+	// URL is the push endpoint, e.g. http://loki:3100/loki/api/v1/push or http://es:9200/_bulk
+	URL string
+	// Format of the body: HTTPSinkJSON posts an array of the lines, HTTPSinkLoki a push request
+	// with one stream per level and HTTPSinkElasticsearch a bulk request
+	Format  string
+	Headers map[string]string
+	// Labels of the Loki streams, "level" is added per entry
+	Labels map[string]string
+	// Index of the Elasticsearch bulk request, an index or a data stream
+	Index  string
+	Client *http.Client
+}
+
+// HTTPSink posts each batch as one request, transport errors, 429 and 5xx are retried by the sink queue
+type HTTPSink struct {
+	opts HTTPSinkOptions
+}
+
+func NewHTTPSink(opts HTTPSinkOptions) (*HTTPSink, error) {
+	if len(opts.URL) == 0 {
+		return nil, fmt.Errorf("logger: http sink url is empty")
+	}
+	switch opts.Format {
+	case "":
+		opts.Format = HTTPSinkJSON
+	case HTTPSinkJSON, HTTPSinkLoki:
+	case HTTPSinkElasticsearch:
+		if len(opts.Index) == 0 {
+			return nil, fmt.Errorf("logger: http sink index is empty")
+		}
+	default:
+		return nil, fmt.Errorf("logger: unknown http sink format %s", opts.Format)
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{}
+	}
+	return &HTTPSink{opts: opts}, nil
+}
+
+func (s *HTTPSink) Write(ctx context.Context, records []Record) error {
+	body, contentType, err := s.body(records)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range s.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return &RetryableError{Err: err}
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		err := fmt.Errorf("http sink status %d: %s", resp.StatusCode, respBody)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+			return &RetryableError{Err: err, After: retryAfter(resp.Header.Get("Retry-After"))}
+		}
+		return err
+	}
+	if s.opts.Format == HTTPSinkElasticsearch {
+		var bulk struct {
+			Errors bool `json:"errors"`
+		}
+		if json.Unmarshal(respBody, &bulk) == nil && bulk.Errors {
+			return fmt.Errorf("http sink bulk request has errors")
+		}
+	}
+	return nil
+}
+
+// retryAfter reads the seconds or the date of a Retry-After header
+func retryAfter(v string) time.Duration {
+	if len(v) == 0 {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func (s *HTTPSink) body(records []Record) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch s.opts.Format {
+	case HTTPSinkLoki:
+		type stream struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		}
+		streams := make(map[string]*stream)
+		var order []string
+		for _, rec := range records {
+			lvl := rec.Entry.Level.String()
+			st, ok := streams[lvl]
+			if !ok {
+				labels := make(map[string]string, len(s.opts.Labels)+1)
+				for k, v := range s.opts.Labels {
+					labels[k] = v
+				}
+				labels["level"] = lvl
+				st = &stream{Stream: labels}
+				streams[lvl] = st
+				order = append(order, lvl)
+			}
+			st.Values = append(st.Values, [2]string{strconv.FormatInt(rec.Entry.Time.UnixNano(), 10), string(rec.Line)})
+		}
+		push := struct {
+			Streams []*stream `json:"streams"`
+		}{}
+		for _, lvl := range order {
+			push.Streams = append(push.Streams, streams[lvl])
+		}
+		b, err := json.Marshal(push)
+		return b, "application/json", err
+	case HTTPSinkElasticsearch:
+		action, err := json.Marshal(map[string]map[string]string{"create": {"_index": s.opts.Index}})
+		if err != nil {
+			return nil, "", err
+		}
+		for _, rec := range records {
+			buf.Write(action)
+			buf.WriteByte('\n')
+			buf.Write(rec.Line)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), "application/x-ndjson", nil
+	}
+	lines := make([]string, len(records))
+	for i, rec := range records {
+		lines[i] = string(rec.Line)
+	}
+	buf.WriteByte('[')
+	buf.WriteString(strings.Join(lines, ","))
+	buf.WriteByte(']')
+	return buf.Bytes(), "application/json", nil
+}
+
+func (s *HTTPSink) Close() error {
+	return nil
+}
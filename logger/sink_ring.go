@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"context"
+	"sync"
+)
+
+// RingSinkName is the name the configured ring is added under, the admin /logs endpoint reads it
+const RingSinkName = "ring"
+
+// RingSink keeps the last lines in memory, e.g. for the admin endpoint
+type RingSink struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+func NewRingSink(size int) *RingSink {
+	if size <= 0 {
+		size = 1000
+	}
+	return &RingSink{lines: make([]string, size)}
+}
+
+func (r *RingSink) Write(ctx context.Context, records []Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rec := range records {
+		r.lines[r.next] = string(rec.Line)
+		r.next++
+		if r.next == len(r.lines) {
+			r.next = 0
+			r.full = true
+		}
+	}
+	return nil
+}
+
+// Lines returns up to the last n lines, oldest first; n <= 0 returns all of them
+func (r *RingSink) Lines(n int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	size := r.next
+	if r.full {
+		size = len(r.lines)
+	}
+	if n <= 0 || n > size {
+		n = size
+	}
+	out := make([]string, 0, n)
+	start := r.next - n
+	if start < 0 {
+		start += len(r.lines)
+	}
+	for i := 0; i < n; i++ {
+		out = append(out, r.lines[(start+i)%len(r.lines)])
+	}
+	return out
+}
+
+func (r *RingSink) Close() error {
+	return nil
+}
@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const rfc5424Time = "2006-01-02T15:04:05.000000Z07:00"
+
+// facility local0, see RFC 5424 section 6.2.1
+const DefaultSyslogFacility = 16
+
+type SyslogOptions struct {
+	// Network is udp, tcp or unixgram, udp by default
+	Network string
+	Address string
+	// Facility is combined with the severity of each entry, DefaultSyslogFacility when zero
+	Facility int
+	AppName  string
+	Hostname string
+}
+
+// SyslogSink sends RFC 5424 messages, over tcp they are framed by octet counting (RFC 6587)
+type SyslogSink struct {
+	opts   SyslogOptions
+	dialer net.Dialer
+	mu     sync.Mutex
+	conn   net.Conn
+	buf    bytes.Buffer
+}
+
+func NewSyslogSink(opts SyslogOptions) *SyslogSink {
+	if len(opts.Network) == 0 {
+		opts.Network = "udp"
+	}
+	if opts.Facility <= 0 {
+		opts.Facility = DefaultSyslogFacility
+	}
+	if len(opts.Hostname) == 0 {
+		opts.Hostname, _ = os.Hostname()
+	}
+	return &SyslogSink{opts: opts}
+}
+
+func (s *SyslogSink) Write(ctx context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		conn, err := s.dialer.DialContext(ctx, s.opts.Network, s.opts.Address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+	}
+	stream := s.opts.Network == "tcp" || s.opts.Network == "tcp4" || s.opts.Network == "tcp6"
+	for _, rec := range records {
+		msg := s.format(rec)
+		if stream {
+			msg = append(strconv.AppendInt(nil, int64(len(msg)), 10), append([]byte{' '}, msg...)...)
+		}
+		if _, err := s.conn.Write(msg); err != nil {
+			// reconnect on the next batch
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// format builds "<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID - MSG" with the logger name as MSGID
+func (s *SyslogSink) format(rec Record) []byte {
+	s.buf.Reset()
+	s.buf.WriteByte('<')
+	s.buf.WriteString(strconv.Itoa(s.opts.Facility*8 + syslogSeverity(rec.Entry.Level)))
+	s.buf.WriteString(">1 ")
+	s.buf.WriteString(rec.Entry.Time.Format(rfc5424Time))
+	s.buf.WriteByte(' ')
+	s.buf.WriteString(syslogField(s.opts.Hostname, 255))
+	s.buf.WriteByte(' ')
+	s.buf.WriteString(syslogField(s.opts.AppName, 48))
+	s.buf.WriteByte(' ')
+	s.buf.WriteString(strconv.Itoa(os.Getpid()))
+	s.buf.WriteByte(' ')
+	s.buf.WriteString(syslogField(rec.Entry.LoggerName, 32))
+	s.buf.WriteString(" - ")
+	s.buf.Write(rec.Line)
+	return bytes.Clone(s.buf.Bytes())
+}
+
+func syslogSeverity(lvl zapcore.Level) int {
+	switch lvl {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel:
+		return 2
+	case zapcore.PanicLevel:
+		return 1
+	}
+	return 0
+}
+
+// syslogField returns "-" for empty values and keeps the printable US-ASCII the header allows
+func syslogField(v string, max int) string {
+	b := make([]byte, 0, len(v))
+	for i := 0; i < len(v) && len(b) < max; i++ {
+		if v[i] > 32 && v[i] < 127 {
+			b = append(b, v[i])
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
+
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSinks(t *testing.T) {
+	old := defaultLogger
+	defaultLogger = newLogger(zap.New(levelCore{newSinkCore()}))
+	t.Cleanup(func() { defaultLogger = old })
+
+	pushed := make(chan map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		pushed <- body
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	loki, err := NewHTTPSink(HTTPSinkOptions{URL: srv.URL, Format: HTTPSinkLoki, Labels: map[string]string{"app": "test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring := NewRingSink(2)
+	if err := AddSink(RingSinkName, ring, SinkOptions{Level: zapcore.DebugLevel, FlushInterval: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddSink("loki", loki, SinkOptions{Level: zapcore.WarnLevel, BatchSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+	defer CloseSinks()
+
+	Named("sink").Info("one")
+	Named("sink").Warn("two")
+	Named("sink").Info("three")
+	select {
+	case body := <-pushed:
+		streams := body["streams"].([]any)
+		stream := streams[0].(map[string]any)
+		labels := stream["stream"].(map[string]any)
+		line := stream["values"].([]any)[0].([]any)[1].(string)
+		if labels["level"] != "warn" || labels["app"] != "test" || !strings.Contains(line, "two") {
+			t.Fatalf("unexpected push %v", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing pushed to loki")
+	}
+	if err := RemoveSink(RingSinkName); err != nil {
+		t.Fatal(err)
+	}
+	lines := ring.Lines(0)
+	if len(lines) != 2 || !strings.Contains(lines[0], "two") || !strings.Contains(lines[1], "three") {
+		t.Fatalf("ring lines %q", lines)
+	}
+}
+
+func TestSinkDrop(t *testing.T) {
+	block := make(chan struct{})
+	s := &funcSink{write: func(ctx context.Context, records []Record) error {
+		<-block
+		return nil
+	}}
+	if err := AddSink("slow", s, SinkOptions{QueueSize: 1, BatchSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+	c := sinkCore{}
+	for i := 0; i < 10; i++ {
+		c.Write(zapcore.Entry{Level: zapcore.InfoLevel, Message: "m"}, nil)
+	}
+	close(block)
+	stats := Sinks()
+	if err := RemoveSink("slow"); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Dropped == 0 {
+		t.Fatalf("stats %+v", stats)
+	}
+}
+
+func TestSinkRetry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	s, err := NewHTTPSink(HTTPSinkOptions{URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AddSink("retry", s, SinkOptions{BatchSize: 1, RetryBackoff: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	sinkCore{}.Write(zapcore.Entry{Level: zapcore.InfoLevel, Message: "m"}, nil)
+	var stats []SinkStats
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if stats = Sinks(); len(stats) == 1 && stats[0].Shipped+stats[0].Failed > 0 {
+			break
+		}
+	}
+	if err := RemoveSink("retry"); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 || len(stats) != 1 || stats[0].Shipped != 1 || stats[0].Failed != 0 {
+		t.Fatalf("calls %d, stats %+v", calls.Load(), stats)
+	}
+}
+
+func TestSyslogFormat(t *testing.T) {
+	s := NewSyslogSink(SyslogOptions{Hostname: "host", AppName: "app"})
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+	msg := string(s.format(Record{Entry: zapcore.Entry{Level: zapcore.ErrorLevel, Time: ts, LoggerName: "lb"}, Line: []byte(`{"rest":"x"}`)}))
+	want := "<131>1 2024-01-02T03:04:05.000006Z host app "
+	if !strings.HasPrefix(msg, want) || !strings.HasSuffix(msg, ` lb - {"rest":"x"}`) {
+		t.Fatalf("syslog message %q", msg)
+	}
+}
+
+type funcSink struct {
+	write func(ctx context.Context, records []Record) error
+}
+
+func (s *funcSink) Write(ctx context.Context, records []Record) error {
+	return s.write(ctx, records)
+}
+
+func (s *funcSink) Close() error {
+	return nil
+}
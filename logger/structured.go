@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/skirrund/gcloud/tracer"
+	"go.uber.org/zap"
+)
+
+// TraceIDField is the key of the trace id the Context methods of Logger add
+const TraceIDField = "traceId"
+
+// Logger writes structured entries: the message is followed by alternating keys and values,
+// e.g. log.Info("order paid", "orderId", id, "amount", amount).
+// Children made with With and Named follow the default logger, also after InitLog replaces it,
+// so they can be created in package variables.
+type Logger struct {
+	zapL  *zap.Logger
+	zapLS *zap.SugaredLogger
+	// derive builds the child from the default logger, nil for the default logger itself
+	derive func(*zap.Logger) *zap.Logger
+	cache  atomic.Pointer[derived]
+}
+
+type derived struct {
+	root  *Logger
+	zapLS *zap.SugaredLogger
+}
+
+func newLogger(zapL *zap.Logger) *Logger {
+	return &Logger{zapL: zapL, zapLS: zapL.Sugar()}
+}
+
+// Named returns a child of the default logger, see Logger.Named
+func Named(name string) *Logger {
+	return Default().Named(name)
+}
+
+// With returns a child of the default logger, see Logger.With
+func With(keysAndValues ...any) *Logger {
+	return Default().With(keysAndValues...)
+}
+
+func (l *Logger) child(f func(*zap.Logger) *zap.Logger) *Logger {
+	parent := l.derive
+	if parent == nil {
+		return &Logger{derive: f}
+	}
+	return &Logger{derive: func(z *zap.Logger) *zap.Logger {
+		return f(parent(z))
+	}}
+}
+
+// Named adds a segment to the logger name, segments are joined with ".".
+// The name selects the level override, see SetNamedLevel.
+func (l *Logger) Named(name string) *Logger {
+	return l.child(func(z *zap.Logger) *zap.Logger {
+		return z.Named(name)
+	})
+}
+
+// With returns a child that adds the key/value pairs to every entry
+func (l *Logger) With(keysAndValues ...any) *Logger {
+	return l.child(func(z *zap.Logger) *zap.Logger {
+		return z.Sugar().With(keysAndValues...).Desugar()
+	})
+}
+
+func (l *Logger) sugar() *zap.SugaredLogger {
+	if l.derive == nil {
+		return l.zapLS
+	}
+	root := Default()
+	if d := l.cache.Load(); d != nil && d.root == root {
+		return d.zapLS
+	}
+	d := &derived{root: root, zapLS: l.derive(root.zapL).Sugar()}
+	l.cache.Store(d)
+	return d.zapLS
+}
+
+// Zap returns the underlying zap logger, its caller skip expects one wrapping frame
+func (l *Logger) Zap() *zap.Logger {
+	return l.sugar().Desugar()
+}
+
+func withTraceID(ctx context.Context, keysAndValues []any) []any {
+	if id := tracer.GetTraceID(ctx); id != nil {
+		return append([]any{TraceIDField, id}, keysAndValues...)
+	}
+	return keysAndValues
+}
+
+func (l *Logger) Debug(msg string, keysAndValues ...any) {
+	l.sugar().Debugw(msg, keysAndValues...)
+}
+
+func (l *Logger) DebugContext(ctx context.Context, msg string, keysAndValues ...any) {
+	l.sugar().Debugw(msg, withTraceID(ctx, keysAndValues)...)
+}
+
+func (l *Logger) Info(msg string, keysAndValues ...any) {
+	l.sugar().Infow(msg, keysAndValues...)
+}
+
+func (l *Logger) InfoContext(ctx context.Context, msg string, keysAndValues ...any) {
+	l.sugar().Infow(msg, withTraceID(ctx, keysAndValues)...)
+}
+
+func (l *Logger) Warn(msg string, keysAndValues ...any) {
+	l.sugar().Warnw(msg, keysAndValues...)
+}
+
+func (l *Logger) WarnContext(ctx context.Context, msg string, keysAndValues ...any) {
+	l.sugar().Warnw(msg, withTraceID(ctx, keysAndValues)...)
+}
+
+func (l *Logger) Error(msg string, keysAndValues ...any) {
+	l.sugar().Errorw(msg, keysAndValues...)
+}
+
+func (l *Logger) ErrorContext(ctx context.Context, msg string, keysAndValues ...any) {
+	l.sugar().Errorw(msg, withTraceID(ctx, keysAndValues)...)
+}
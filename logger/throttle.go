@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/time/rate"
+)
+
+// Sampling is zap's sampler: per Tick the first First entries with the same level and message
+// are logged, after that every Thereafter-th. Messages that differ per call, e.g. with the trace id
+// added by InfoContext, aren't sampled; the structured Logger keeps the trace id in a field.
+type Sampling struct {
+	Tick       time.Duration
+	First      int
+	Thereafter int
+}
+
+// RateLimit caps the entries whose message contains Key to Rate per second with bursts of Burst
+type RateLimit struct {
+	Key   string  `mapstructure:"key" json:"key"`
+	Rate  float64 `mapstructure:"rate" json:"rate"`
+	Burst int     `mapstructure:"burst" json:"burst"`
+}
+
+type limit struct {
+	key     string
+	limiter *rate.Limiter
+}
+
+type throttle struct {
+	sampler zapcore.Core
+	limits  []limit
+}
+
+var (
+	throttling  atomic.Pointer[throttle]
+	throttleMu  sync.Mutex
+	sampledOut  atomic.Uint64
+	rateLimited atomic.Uint64
+	// sampledIn is what passCore returns, telling that the sampler let the entry through
+	sampledIn = &zapcore.CheckedEntry{}
+)
+
+// SetSampling samples every logger, nil turns sampling off
+func SetSampling(s *Sampling) {
+	throttleMu.Lock()
+	defer throttleMu.Unlock()
+	t := currentThrottle()
+	t.sampler = nil
+	if s != nil {
+		tick := s.Tick
+		if tick <= 0 {
+			tick = time.Second
+		}
+		t.sampler = zapcore.NewSamplerWithOptions(passCore{}, tick, max(s.First, 1), max(s.Thereafter, 0))
+	}
+	throttling.Store(t)
+}
+
+// SetRateLimits replaces the rate limits, an entry is checked against the first limit whose key it contains
+func SetRateLimits(limits []RateLimit) {
+	throttleMu.Lock()
+	defer throttleMu.Unlock()
+	t := currentThrottle()
+	t.limits = nil
+	for _, l := range limits {
+		if len(l.Key) == 0 {
+			continue
+		}
+		t.limits = append(t.limits, limit{key: l.Key, limiter: rate.NewLimiter(rate.Limit(l.Rate), max(l.Burst, 1))})
+	}
+	throttling.Store(t)
+}
+
+// Throttled returns the entries dropped by sampling and by the rate limits so far
+func Throttled() (sampled, limited uint64) {
+	return sampledOut.Load(), rateLimited.Load()
+}
+
+// currentThrottle returns a copy of the settings to modify
+func currentThrottle() *throttle {
+	if t := throttling.Load(); t != nil {
+		c := *t
+		return &c
+	}
+	return &throttle{}
+}
+
+func (t *throttle) allow(ent zapcore.Entry) bool {
+	for _, l := range t.limits {
+		if strings.Contains(ent.Message, l.key) {
+			if !l.limiter.Allow() {
+				rateLimited.Add(1)
+				return false
+			}
+			break
+		}
+	}
+	if t.sampler != nil && t.sampler.Check(ent, nil) != sampledIn {
+		sampledOut.Add(1)
+		return false
+	}
+	return true
+}
+
+// throttleCore applies sampling and the rate limits behind levelCore,
+// so entries filtered by level don't use up the budget
+type throttleCore struct {
+	zapcore.Core
+}
+
+func (c throttleCore) With(fields []zapcore.Field) zapcore.Core {
+	return throttleCore{c.Core.With(fields)}
+}
+
+func (c throttleCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if t := throttling.Load(); t != nil && !t.allow(ent) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// passCore only serves the sampling decision of zap's sampler
+type passCore struct{}
+
+func (passCore) Enabled(zapcore.Level) bool                 { return true }
+func (c passCore) With([]zapcore.Field) zapcore.Core        { return c }
+func (passCore) Write(zapcore.Entry, []zapcore.Field) error { return nil }
+func (passCore) Sync() error                                { return nil }
+func (passCore) Check(zapcore.Entry, *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return sampledIn
+}
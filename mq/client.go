@@ -15,6 +15,12 @@ type IClient interface {
 	Close()
 }
 
+// Pinger is implemented by clients that can check their connection to the broker,
+// Bootstrap reports the mq component down while Ping fails
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
 type ACKMode uint32
 
 const (
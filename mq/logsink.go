@@ -0,0 +1,43 @@
+package mq
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/skirrund/gcloud/logger"
+)
+
+// LogSink is a logger.Sink publishing one message per entry with the JSON line as payload,
+// e.g. to a Kafka-compatible broker. The level and logger name are set as headers.
+type LogSink struct {
+	client IClient
+	topic  string
+}
+
+func NewLogSink(client IClient, topic string) *LogSink {
+	return &LogSink{client: client, topic: topic}
+}
+
+func (s *LogSink) Write(ctx context.Context, records []logger.Record) error {
+	var errs []error
+	for _, rec := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		msg := &Message{
+			Topic:   s.topic,
+			Header:  map[string]string{"level": rec.Entry.Level.String(), "logger": rec.Entry.LoggerName},
+			Payload: bytes.Clone(rec.Line),
+		}
+		if err := s.client.SendAsync(msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close leaves the client open, it belongs to the caller
+func (s *LogSink) Close() error {
+	return nil
+}
@@ -0,0 +1,69 @@
+package otel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/skirrund/gcloud/tracer"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// gormParentKey keeps the statement context of before the span, a reused statement must not
+// start its next span under the ended one
+const gormParentKey = "otel:parent"
+
+// InstrumentGorm records a client span for every create, query, update, delete, row and raw statement of db.
+// The span is a child of the statement context, e.g. of database.GetWithContext(ctx).
+// The datasources of package database are instrumented when Enabled.
+func InstrumentGorm(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("otel:before_create", gormBefore("insert")),
+		cb.Create().After("gorm:create").Register("otel:after_create", gormAfter),
+		cb.Query().Before("gorm:query").Register("otel:before_query", gormBefore("select")),
+		cb.Query().After("gorm:query").Register("otel:after_query", gormAfter),
+		cb.Update().Before("gorm:update").Register("otel:before_update", gormBefore("update")),
+		cb.Update().After("gorm:update").Register("otel:after_update", gormAfter),
+		cb.Delete().Before("gorm:delete").Register("otel:before_delete", gormBefore("delete")),
+		cb.Delete().After("gorm:delete").Register("otel:after_delete", gormAfter),
+		cb.Row().Before("gorm:row").Register("otel:before_row", gormBefore("row")),
+		cb.Row().After("gorm:row").Register("otel:after_row", gormAfter),
+		cb.Raw().Before("gorm:raw").Register("otel:before_raw", gormBefore("raw")),
+		cb.Raw().After("gorm:raw").Register("otel:after_raw", gormAfter),
+	)
+}
+
+func gormBefore(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		parent := tx.Statement.Context
+		name := operation
+		if len(tx.Statement.Table) > 0 {
+			name += " " + tx.Statement.Table
+		}
+		ctx, _ := tracer.Start(parent, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			semconv.DBSystemNameKey.String(tx.Dialector.Name()),
+			semconv.DBOperationName(operation),
+			semconv.DBCollectionName(tx.Statement.Table),
+		))
+		tx.InstanceSet(gormParentKey, parent)
+		tx.Statement.Context = ctx
+	}
+}
+
+func gormAfter(tx *gorm.DB) {
+	parent, ok := tx.InstanceGet(gormParentKey)
+	if !ok {
+		return
+	}
+	span := trace.SpanFromContext(tx.Statement.Context)
+	span.SetAttributes(semconv.DBQueryText(tx.Statement.SQL.String()))
+	if err := tx.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	tx.Statement.Context = parent.(context.Context)
+}
@@ -0,0 +1,107 @@
+package otel
+
+import (
+	"context"
+
+	"github.com/skirrund/gcloud/mq"
+	"github.com/skirrund/gcloud/tracer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Client traces an mq.IClient: a send starts a producer span and writes the trace context and the
+// legacy trace id into Message.Header, a listener runs in a consumer span continuing it
+type Client struct {
+	mq.IClient
+	system string
+}
+
+// WrapClient wraps c, system names the broker in the spans, e.g. "pulsar" or "nats"
+func WrapClient(c mq.IClient, system string) *Client {
+	return &Client{IClient: c, system: system}
+}
+
+// Send starts a new trace, use SendContext to continue the one of a request
+func (c *Client) Send(msg *mq.Message) error {
+	return c.SendContext(context.Background(), msg)
+}
+
+func (c *Client) SendContext(ctx context.Context, msg *mq.Message) error {
+	return c.send(ctx, msg, c.IClient.Send)
+}
+
+func (c *Client) SendAsync(msg *mq.Message) error {
+	return c.SendAsyncContext(context.Background(), msg)
+}
+
+// SendAsyncContext ends the producer span when the message is handed to the client, not when it is acknowledged
+func (c *Client) SendAsyncContext(ctx context.Context, msg *mq.Message) error {
+	return c.send(ctx, msg, c.IClient.SendAsync)
+}
+
+func (c *Client) send(ctx context.Context, msg *mq.Message, send func(*mq.Message) error) error {
+	ctx, span := tracer.Start(ctx, "send "+msg.Topic, trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(c.attributes(msg, semconv.MessagingOperationTypeSend)...))
+	if msg.Header == nil {
+		msg.Header = make(map[string]string)
+	}
+	tracer.Inject(ctx, propagation.MapCarrier(msg.Header))
+	if _, ok := msg.Header[tracer.TraceIDKey]; !ok {
+		if id, ok := tracer.GetTraceID(ctx).(string); ok {
+			msg.Header[tracer.TraceIDKey] = id
+		}
+	}
+	err := send(msg)
+	endSpan(span, err)
+	return err
+}
+
+func (c *Client) Subscribe(options mq.ConsumerOptions) error {
+	options.MessageListener = c.listener(options.MessageListener)
+	return c.IClient.Subscribe(options)
+}
+
+func (c *Client) SubscribeSync(options mq.ConsumerOptions) error {
+	options.MessageListener = c.listener(options.MessageListener)
+	return c.IClient.SubscribeSync(options)
+}
+
+func (c *Client) listener(next func(ctx context.Context, message *mq.Message) error) func(ctx context.Context, message *mq.Message) error {
+	if next == nil {
+		return nil
+	}
+	return func(ctx context.Context, msg *mq.Message) error {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx = tracer.Extract(ctx, propagation.MapCarrier(msg.Header))
+		if id := msg.Header[tracer.TraceIDKey]; len(id) > 0 {
+			ctx = tracer.WithContext(ctx, id)
+		}
+		ctx, span := tracer.Start(ctx, "process "+msg.Topic, trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(c.attributes(msg, semconv.MessagingOperationTypeProcess)...))
+		err := next(ctx, msg)
+		endSpan(span, err)
+		return err
+	}
+}
+
+func (c *Client) attributes(msg *mq.Message, operation attribute.KeyValue) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.MessagingSystemKey.String(c.system),
+		semconv.MessagingDestinationName(msg.Topic),
+		semconv.MessagingMessageBodySize(len(msg.Payload)),
+		operation,
+	}
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,192 @@
+// Package otel installs an OpenTelemetry TracerProvider exporting through OTLP/HTTP or stdout,
+// with the W3C trace context and baggage propagators. The gin TraceMiddleware and the lb http
+// client propagate through it, and gorm, go-redis and mq clients can be instrumented here.
+package otel
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/server"
+	"github.com/skirrund/gcloud/tracer"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	OTEL_ENABLED_KEY      = "otel.enabled"
+	OTEL_EXPORTER_KEY     = "otel.exporter"
+	OTEL_ENDPOINT_KEY     = "otel.endpoint"
+	OTEL_INSECURE_KEY     = "otel.insecure"
+	OTEL_HEADERS_KEY      = "otel.headers"
+	OTEL_SAMPLE_RATIO_KEY = "otel.sampleRatio"
+	OTEL_SERVICE_NAME_KEY = "otel.serviceName"
+
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+
+	DefaultShutdownTimeout = 5 * time.Second
+)
+
+type Options struct {
+	ServiceName string
+	// Exporter is ExporterOTLP (default) or ExporterStdout
+	Exporter string
+	// Endpoint is host:port or a URL of the OTLP/HTTP collector,
+	// the OTEL_EXPORTER_OTLP_* environment variables apply when empty
+	Endpoint string
+	Insecure bool
+	Headers  map[string]string
+	// SampleRatio of the new root traces, remote parents decide for their children
+	SampleRatio float64
+}
+
+var (
+	mu       sync.Mutex
+	provider *sdktrace.TracerProvider
+)
+
+// Enabled reports whether otel.enabled is true, the datasource and the redis client instrument themselves then.
+// Spans started before InitDefault installs the provider are no-ops.
+func Enabled() bool {
+	return env.GetInstance().GetBool(OTEL_ENABLED_KEY)
+}
+
+// InitDefault initializes tracing from otel.*, it does nothing unless otel.enabled is true
+func InitDefault() error {
+	cfg := env.GetInstance()
+	if !Enabled() {
+		return nil
+	}
+	opts := Options{
+		ServiceName: cfg.GetStringWithDefault(OTEL_SERVICE_NAME_KEY, cfg.GetString(env.SERVER_SERVERNAME_KEY)),
+		Exporter:    cfg.GetString(OTEL_EXPORTER_KEY),
+		Endpoint:    cfg.GetString(OTEL_ENDPOINT_KEY),
+		Insecure:    cfg.GetBool(OTEL_INSECURE_KEY),
+		Headers:     cfg.GetStringMapString(OTEL_HEADERS_KEY),
+		SampleRatio: 1,
+	}
+	if cfg.Get(OTEL_SAMPLE_RATIO_KEY) != nil {
+		opts.SampleRatio = cfg.GetFloat64(OTEL_SAMPLE_RATIO_KEY)
+	}
+	return Init(opts)
+}
+
+// Init installs the global TracerProvider and propagator, the provider is flushed on ShutdownEvent
+func Init(opts Options) error {
+	exp, err := newExporter(opts)
+	if err != nil {
+		logger.Error("[otel] exporter error:", err.Error())
+		return err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+		sdktrace.WithIDGenerator(IDGenerator{}),
+	)
+	mu.Lock()
+	old := provider
+	provider = tp
+	mu.Unlock()
+	if old != nil {
+		shutdown(old)
+	} else {
+		server.RegisterEventHook(server.ShutdownEvent, func(eventType server.EventName, eventInfo any) error {
+			return Shutdown()
+		})
+	}
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	logger.Info("[otel] tracing enabled, exporter:", opts.Exporter, ",endpoint:", opts.Endpoint, ",sampleRatio:", opts.SampleRatio)
+	return nil
+}
+
+func newExporter(opts Options) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(opts.Exporter) {
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "", ExporterOTLP:
+		var hopts []otlptracehttp.Option
+		if strings.Contains(opts.Endpoint, "://") {
+			hopts = append(hopts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		} else if len(opts.Endpoint) > 0 {
+			hopts = append(hopts, otlptracehttp.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			hopts = append(hopts, otlptracehttp.WithInsecure())
+		}
+		if len(opts.Headers) > 0 {
+			hopts = append(hopts, otlptracehttp.WithHeaders(opts.Headers))
+		}
+		return otlptracehttp.New(context.Background(), hopts...)
+	}
+	return nil, errors.New("[otel] unknown exporter: " + opts.Exporter)
+}
+
+// Shutdown flushes the pending spans within DefaultShutdownTimeout and stops the provider installed by Init
+func Shutdown() error {
+	mu.Lock()
+	tp := provider
+	provider = nil
+	mu.Unlock()
+	if tp == nil {
+		return nil
+	}
+	return shutdown(tp)
+}
+
+func shutdown(tp *sdktrace.TracerProvider) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+	defer cancel()
+	err := tp.Shutdown(ctx)
+	if err != nil {
+		logger.Error("[otel] shutdown error:", err.Error())
+	}
+	return err
+}
+
+// IDGenerator uses the legacy trace id of the context for new root spans,
+// so logs written with the legacy id and the exported trace share one id
+type IDGenerator struct{}
+
+func (IDGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	tid, ok := tracer.LegacyTraceID(ctx)
+	for !ok || !tid.IsValid() {
+		binary.BigEndian.PutUint64(tid[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(tid[8:], rand.Uint64())
+		ok = true
+	}
+	return tid, newSpanID()
+}
+
+func (IDGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	return newSpanID()
+}
+
+func newSpanID() trace.SpanID {
+	var sid trace.SpanID
+	for !sid.IsValid() {
+		binary.BigEndian.PutUint64(sid[:], rand.Uint64())
+	}
+	return sid
+}
@@ -0,0 +1,144 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/mq"
+	gm "github.com/skirrund/gcloud/plugins/server/http/gin/middleware"
+	"github.com/skirrund/gcloud/tracer"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func setup(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr), sdktrace.WithIDGenerator(IDGenerator{}))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+	return sr
+}
+
+func TestLegacyTraceID(t *testing.T) {
+	setup(t)
+	id := tracer.GenerateId()
+	ctx, span := tracer.Start(tracer.NewContextFromTraceId(id), "root")
+	defer span.End()
+	if got := span.SpanContext().TraceID().String(); got != id {
+		t.Fatalf("trace id %s, want the legacy id %s", got, id)
+	}
+	if tracer.GetTraceID(context.Background()) != nil {
+		t.Fatal("unexpected trace id without span")
+	}
+	if got := tracer.GetTraceID(trace.ContextWithSpanContext(context.Background(), span.SpanContext())); got != id {
+		t.Fatalf("span fallback %v, want %s", got, id)
+	}
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	if child.SpanContext().TraceID() != span.SpanContext().TraceID() {
+		t.Fatal("child left the trace")
+	}
+}
+
+func TestTraceMiddleware(t *testing.T) {
+	sr := setup(t)
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(gm.TraceMiddleware)
+	var logged any
+	e.GET("/user/:id", func(c *gin.Context) {
+		logged = tracer.GetTraceID(c.Request.Context())
+		c.Status(http.StatusNoContent)
+	})
+
+	parent := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/user/1", nil)
+	req.Header.Set("traceparent", "00-"+parent+"-00f067aa0ba902b7-01")
+	req.Header.Set(tracer.TraceIDKey, "legacy")
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	if got := w.Header().Get(tracer.TraceIDKey); got != parent || logged != parent {
+		t.Fatalf("header %s, logged %v, want the traceparent id %s", got, logged, parent)
+	}
+	spans := sr.Ended()
+	if len(spans) != 1 || spans[0].Name() != "GET /user/:id" || spans[0].Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+
+	legacy := tracer.GenerateId()
+	req = httptest.NewRequest(http.MethodGet, "/user/2", nil)
+	req.Header.Set(tracer.TraceIDKey, legacy)
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	spans = sr.Ended()
+	if got := spans[len(spans)-1].SpanContext().TraceID().String(); got != legacy || w.Header().Get(tracer.TraceIDKey) != legacy {
+		t.Fatalf("root trace id %s, header %s, want the legacy id %s", got, w.Header().Get(tracer.TraceIDKey), legacy)
+	}
+}
+
+// loopback delivers every sent message to the subscribed listener
+type loopback struct {
+	listener func(ctx context.Context, message *mq.Message) error
+}
+
+func (l *loopback) Send(msg *mq.Message) error {
+	return l.listener(context.Background(), msg)
+}
+
+func (l *loopback) SendAsync(msg *mq.Message) error {
+	return l.Send(msg)
+}
+
+func (l *loopback) Subscribe(options mq.ConsumerOptions) error {
+	l.listener = options.MessageListener
+	return nil
+}
+
+func (l *loopback) SubscribeSync(options mq.ConsumerOptions) error {
+	return l.Subscribe(options)
+}
+
+func (l *loopback) Close() {}
+
+func TestClient(t *testing.T) {
+	sr := setup(t)
+	c := WrapClient(&loopback{}, "test")
+	var consumed trace.SpanContext
+	var logged any
+	c.Subscribe(mq.ConsumerOptions{Topic: "orders", MessageListener: func(ctx context.Context, message *mq.Message) error {
+		consumed = trace.SpanContextFromContext(ctx)
+		logged = tracer.GetTraceID(ctx)
+		return nil
+	}})
+	ctx, span := tracer.Start(tracer.NewTraceIDContext(), "request")
+	msg := &mq.Message{Topic: "orders", Payload: []byte("{}")}
+	if err := c.SendContext(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	span.End()
+	tid := span.SpanContext().TraceID()
+	if consumed.TraceID() != tid || logged != tid.String() || msg.Header[tracer.TraceIDKey] != tid.String() {
+		t.Fatalf("consumer trace %s, logged %v, header %v, want %s", consumed.TraceID(), logged, msg.Header, tid)
+	}
+	if len(msg.Header["traceparent"]) == 0 {
+		t.Fatal("traceparent not injected")
+	}
+	kinds := map[trace.SpanKind]bool{}
+	for _, s := range sr.Ended() {
+		kinds[s.SpanKind()] = true
+	}
+	if !kinds[trace.SpanKindProducer] || !kinds[trace.SpanKindConsumer] {
+		t.Fatalf("missing producer or consumer span: %v", kinds)
+	}
+}
@@ -0,0 +1,35 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+)
+
+const (
+	ADMIN_ENABLED_KEY   = "server.admin.enabled"
+	ADMIN_PATH_KEY      = "server.admin.path"
+	ADMIN_MASK_KEYS_KEY = "server.admin.maskKeys"
+	DefaultPath         = "/admin"
+)
+
+// Register mounts the admin routes on engine when server.admin.enabled is true
+func Register(engine *gin.Engine) {
+	cfg := env.GetInstance()
+	if !cfg.GetBool(ADMIN_ENABLED_KEY) {
+		return
+	}
+	path := cfg.GetStringWithDefault(ADMIN_PATH_KEY, DefaultPath)
+	logger.Info("[admin] register admin routes:", path)
+	RegisterGroup(engine.Group(path))
+}
+
+// RegisterGroup mounts the admin routes on an existing group,
+// so callers can protect it with their own middleware
+func RegisterGroup(group *gin.RouterGroup) {
+	group.GET("/config", ConfigHandler)
+	group.GET("/loggers", LoggersHandler)
+	group.PUT("/loggers", SetLoggerLevelHandler)
+	group.GET("/logs", LogsHandler)
+	group.GET("/logs/sinks", LogSinksHandler)
+}
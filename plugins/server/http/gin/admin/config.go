@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/response"
+	"github.com/skirrund/gcloud/utils/redact"
+)
+
+// default key fragments whose values are masked
+var defaultMaskKeys = []string{"password", "passwd", "secret", "token", "accesskey", "privatekey", "credential", "dsn"}
+
+type configEntry struct {
+	Key       string     `json:"key"`
+	Value     string     `json:"value"`
+	Source    env.Source `json:"source"`
+	Detail    string     `json:"detail,omitempty"`
+	UpdatedAt string     `json:"updatedAt,omitempty"`
+}
+
+// ConfigHandler lists the effective config keys with masked secrets and their origin
+func ConfigHandler(ctx *gin.Context) {
+	cfg := env.GetInstance()
+	maskKeys := append(defaultMaskKeys, cfg.GetStringSlice(ADMIN_MASK_KEYS_KEY)...)
+	prefix := strings.ToLower(ctx.Query("prefix"))
+	entries := cfg.Entries()
+	result := make([]configEntry, 0, len(entries))
+	for _, e := range entries {
+		if len(prefix) > 0 && !strings.HasPrefix(e.Key, prefix) {
+			continue
+		}
+		ce := configEntry{
+			Key:    e.Key,
+			Value:  fmt.Sprint(e.Value),
+			Source: e.Source,
+			Detail: e.Detail,
+		}
+		if isSensitive(e.Key, maskKeys) {
+			ce.Value = redact.Mask(ce.Value)
+		}
+		if !e.UpdatedAt.IsZero() {
+			ce.UpdatedAt = e.UpdatedAt.Format(time.DateTime)
+		}
+		result = append(result, ce)
+	}
+	ctx.JSON(200, response.Success(result))
+}
+
+func isSensitive(key string, maskKeys []string) bool {
+	k := strings.ToLower(key)
+	for _, m := range maskKeys {
+		if len(m) > 0 && strings.Contains(k, strings.ToLower(m)) {
+			return true
+		}
+	}
+	return false
+}
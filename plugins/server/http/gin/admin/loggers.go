@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/response"
+)
+
+type loggerLevels struct {
+	Level string            `json:"level"`
+	Named map[string]string `json:"named"`
+}
+
+// loggerLevelReq changes the base level when Name is empty, an empty Level removes the override of Name
+type loggerLevelReq struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+}
+
+// LoggersHandler lists the base level and the per-name overrides
+func LoggersHandler(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, response.Success(currentLevels()))
+}
+
+// SetLoggerLevelHandler changes a level until the next change of logger.level or logger.levels
+func SetLoggerLevelHandler(ctx *gin.Context) {
+	var req loggerLevelReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	if len(req.Name) > 0 && len(req.Level) == 0 {
+		logger.UnsetNamedLevel(req.Name)
+		logger.Info("[admin] logger level override removed:", req.Name)
+		ctx.JSON(http.StatusOK, response.Success(currentLevels()))
+		return
+	}
+	lvl, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	if len(req.Name) == 0 {
+		logger.SetLevel(lvl)
+	} else {
+		logger.SetNamedLevel(req.Name, lvl)
+	}
+	logger.Info("[admin] logger level changed:", req.Name, "=>", lvl)
+	ctx.JSON(http.StatusOK, response.Success(currentLevels()))
+}
+
+func currentLevels() loggerLevels {
+	ll := loggerLevels{Level: logger.GetLevel().String(), Named: make(map[string]string)}
+	for name, lvl := range logger.NamedLevels() {
+		ll.Named[name] = lvl.String()
+	}
+	return ll
+}
+
+func badRequest(ctx *gin.Context, subMsg string) {
+	resp := response.CreateMsgInfoResult[any](response.VALIDATE_API_ERROR, nil)
+	resp.SubMessage = subMsg
+	ctx.AbortWithStatusJSON(http.StatusBadRequest, resp)
+}
@@ -0,0 +1,32 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/response"
+)
+
+// LogsHandler returns the last n lines (default 100) of the ring sink, ?sink= selects another ring
+func LogsHandler(ctx *gin.Context) {
+	n, err := strconv.Atoi(ctx.DefaultQuery("n", "100"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	name := ctx.DefaultQuery("sink", logger.RingSinkName)
+	s, _ := logger.LookupSink(name)
+	ring, ok := s.(*logger.RingSink)
+	if !ok {
+		badRequest(ctx, "no ring sink "+name+", see logger.sinks.ring.enabled")
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Success(ring.Lines(n)))
+}
+
+// LogSinksHandler lists the sinks with their shipped, dropped and failed counters
+func LogSinksHandler(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, response.Success(logger.Sinks()))
+}
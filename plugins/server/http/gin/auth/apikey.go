@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/cache/redis"
+	"github.com/skirrund/gcloud/utils"
+)
+
+const (
+	MethodAPIKey          = "apikey"
+	DefaultAPIKeyHeader   = "X-API-Key"
+	DefaultAPIKeyRedisKey = "gcloud:apikey:"
+)
+
+var (
+	errUnknownAPIKey    = errors.New("unknown api key")
+	errRedisUnavailable = errors.New("redis client is not initialized")
+)
+
+type APIKey struct {
+	Key         string   `json:"key,omitempty"`
+	Subject     string   `json:"subject"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// KeyStore finds the API key entry of a presented key, it returns nil, nil for unknown keys
+type KeyStore interface {
+	Lookup(ctx context.Context, key string) (*APIKey, error)
+}
+
+// HashAPIKey is how stores index keys, so the keys themselves are never kept or compared directly
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// StaticKeyStore holds keys from config
+type StaticKeyStore map[string]APIKey
+
+func NewStaticKeyStore(keys ...APIKey) StaticKeyStore {
+	s := make(StaticKeyStore, len(keys))
+	for _, k := range keys {
+		if len(k.Key) == 0 {
+			continue
+		}
+		hash := HashAPIKey(k.Key)
+		k.Key = ""
+		s[hash] = k
+	}
+	return s
+}
+
+func (s StaticKeyStore) Lookup(ctx context.Context, key string) (*APIKey, error) {
+	if k, ok := s[HashAPIKey(key)]; ok {
+		return &k, nil
+	}
+	return nil, nil
+}
+
+// RedisKeyStore reads the JSON APIKey stored at prefix + HashAPIKey(key),
+// a nil client resolves the default redis client on first lookup
+type RedisKeyStore struct {
+	client atomic.Pointer[redis.RedisClient]
+	prefix string
+}
+
+func NewRedisKeyStore(client *redis.RedisClient, prefix string) *RedisKeyStore {
+	if len(prefix) == 0 {
+		prefix = DefaultAPIKeyRedisKey
+	}
+	s := &RedisKeyStore{prefix: prefix}
+	if client != nil {
+		s.client.Store(client)
+	}
+	return s
+}
+
+func (s *RedisKeyStore) get() (*redis.RedisClient, error) {
+	if c := s.client.Load(); c != nil {
+		return c, nil
+	}
+	c, err := resolveRedis()
+	if err != nil {
+		return nil, err
+	}
+	s.client.Store(c)
+	return c, nil
+}
+
+func resolveRedis() (c *redis.RedisClient, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("redis client: %v", r)
+		}
+	}()
+	c = redis.GetClient()
+	if c == nil || c.Client() == nil {
+		return nil, errRedisUnavailable
+	}
+	return c, nil
+}
+
+func (s *RedisKeyStore) Lookup(ctx context.Context, key string) (*APIKey, error) {
+	c, err := s.get()
+	if err != nil {
+		return nil, err
+	}
+	v := c.Get(s.prefix + HashAPIKey(key))
+	if len(v) == 0 {
+		return nil, nil
+	}
+	k := &APIKey{}
+	if err := utils.UnmarshalFromString(v, k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// APIKeyAuthenticator accepts keys presented in a header
+type APIKeyAuthenticator struct {
+	header string
+	store  KeyStore
+}
+
+func NewAPIKey(header string, store KeyStore) *APIKeyAuthenticator {
+	if len(header) == 0 {
+		header = DefaultAPIKeyHeader
+	}
+	return &APIKeyAuthenticator{header: header, store: store}
+}
+
+func (a *APIKeyAuthenticator) Authenticate(ctx *gin.Context) (*Claims, error) {
+	key := ctx.GetHeader(a.header)
+	if len(key) == 0 {
+		return nil, ErrNoCredentials
+	}
+	k, err := a.store.Lookup(ctx.Request.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return nil, errUnknownAPIKey
+	}
+	return &Claims{
+		Subject:     k.Subject,
+		Roles:       k.Roles,
+		Permissions: k.Permissions,
+		Method:      MethodAPIKey,
+		Raw:         map[string]any{"subject": k.Subject},
+	}, nil
+}
@@ -0,0 +1,141 @@
+// Package auth authenticates gin requests with JWTs or API keys and checks roles and permissions.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/logger"
+	gm "github.com/skirrund/gcloud/plugins/server/http/gin/middleware"
+	"github.com/skirrund/gcloud/response"
+)
+
+// ClaimsKey is the gin context key of the authenticated *Claims
+const ClaimsKey = "gcloud.auth.claims"
+
+// ErrNoCredentials is returned by an Authenticator when the request carries none of its credentials
+var ErrNoCredentials = errors.New("no credentials")
+
+type Claims struct {
+	Subject     string
+	Roles       []string
+	Permissions []string
+	// Method is the authenticator that produced the claims, "jwt" or "apikey"
+	Method string
+	// Raw holds every claim of a JWT or the stored attributes of an API key
+	Raw map[string]any
+}
+
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, r := range roles {
+		if slices.Contains(c.Roles, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Claims) HasPermissions(perms ...string) bool {
+	for _, p := range perms {
+		if !slices.Contains(c.Permissions, p) {
+			return false
+		}
+	}
+	return true
+}
+
+type Authenticator interface {
+	Authenticate(ctx *gin.Context) (*Claims, error)
+}
+
+type claimsCtxKey struct{}
+
+func WithClaims(ctx context.Context, c *Claims) context.Context {
+	return context.WithValue(ctx, claimsCtxKey{}, c)
+}
+
+// FromContext returns the claims of a request context or of a *gin.Context
+func FromContext(ctx context.Context) (*Claims, bool) {
+	if gc, ok := ctx.(*gin.Context); ok {
+		if v, ok := gc.Get(ClaimsKey); ok {
+			c, ok := v.(*Claims)
+			return c, ok
+		}
+		ctx = gc.Request.Context()
+	}
+	c, ok := ctx.Value(claimsCtxKey{}).(*Claims)
+	return c, ok
+}
+
+func setClaims(ctx *gin.Context, c *Claims) {
+	ctx.Set(ClaimsKey, c)
+	ctx.Set(gm.UserIDKey, c.Subject)
+	ctx.Request = ctx.Request.WithContext(WithClaims(ctx.Request.Context(), c))
+}
+
+func deny(ctx *gin.Context, status int, subMsg string) {
+	ctx.AbortWithStatusJSON(status, response.CreateMsgInfo[any](response.ACCESS_PERM_DENIED, subMsg))
+}
+
+// Authenticate tries the authenticators in order and answers 401 when none of them accepts the request
+func Authenticate(authenticators ...Authenticator) gin.HandlerFunc {
+	return authenticate(true, authenticators)
+}
+
+// Optional is Authenticate for routes that also serve anonymous requests,
+// only invalid credentials are rejected
+func Optional(authenticators ...Authenticator) gin.HandlerFunc {
+	return authenticate(false, authenticators)
+}
+
+func authenticate(required bool, authenticators []Authenticator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		for _, a := range authenticators {
+			c, err := a.Authenticate(ctx)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			if err != nil {
+				logger.WarnContext(ctx, "[auth] reject:", ctx.Request.URL.Path, ",", err.Error())
+				deny(ctx, http.StatusUnauthorized, "invalid credentials")
+				return
+			}
+			setClaims(ctx, c)
+			ctx.Next()
+			return
+		}
+		if required {
+			deny(ctx, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RequireRoles lets the request through when the caller has any of the roles
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return require(func(c *Claims) bool { return c.HasRole(roles...) })
+}
+
+// RequirePermissions lets the request through when the caller has all of the permissions
+func RequirePermissions(perms ...string) gin.HandlerFunc {
+	return require(func(c *Claims) bool { return c.HasPermissions(perms...) })
+}
+
+func require(check func(*Claims) bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, ok := FromContext(ctx)
+		if !ok {
+			deny(ctx, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !check(c) {
+			deny(ctx, http.StatusForbidden, "")
+			return
+		}
+		ctx.Next()
+	}
+}
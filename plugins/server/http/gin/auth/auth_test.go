@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/skirrund/gcloud/utils"
+)
+
+func newEngine(a ...Authenticator) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	g := e.Group("/", Authenticate(a...))
+	g.GET("/me", func(ctx *gin.Context) {
+		c, _ := FromContext(ctx.Request.Context())
+		ctx.String(http.StatusOK, c.Subject)
+	})
+	g.GET("/admin", RequireRoles("admin"), func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	return e
+}
+
+func do(e *gin.Engine, path string, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if len(header) > 0 {
+		req.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestJWTSecret(t *testing.T) {
+	a, err := NewJWT(JWTConfig{Secret: "s3cret", Issuer: "gcloud"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := newEngine(a)
+	sign := func(claims jwt.MapClaims, key string) string {
+		s, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(key))
+		return "Bearer " + s
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	user := sign(jwt.MapClaims{"sub": "u1", "iss": "gcloud", "exp": exp, "roles": []string{"user"}}, "s3cret")
+	if w := do(e, "/me", "Authorization", user); w.Code != http.StatusOK || w.Body.String() != "u1" {
+		t.Fatalf("valid token: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(e, "/admin", "Authorization", user); w.Code != http.StatusForbidden {
+		t.Fatalf("missing role: %d", w.Code)
+	}
+	if w := do(e, "/me", "Authorization", sign(jwt.MapClaims{"sub": "u1", "iss": "gcloud", "exp": exp}, "other")); w.Code != http.StatusUnauthorized {
+		t.Fatalf("bad signature: %d", w.Code)
+	}
+	if w := do(e, "/me", "", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("no token: %d", w.Code)
+	}
+}
+
+func TestJWKSFile(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	set := map[string]any{"keys": []map[string]string{{
+		"kid": "k1",
+		"kty": "RSA",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	b, _ := utils.Marshal(set)
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	os.WriteFile(file, b, 0o600)
+	a, err := NewJWT(JWTConfig{JWKSFile: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "svc", "exp": time.Now().Add(time.Hour).Unix(), "roles": "admin ops"})
+	tok.Header["kid"] = "k1"
+	s, _ := tok.SignedString(key)
+	if w := do(newEngine(a), "/admin", "Authorization", "Bearer "+s); w.Code != http.StatusOK {
+		t.Fatalf("jwks token: %d %s", w.Code, w.Body.String())
+	}
+	// an hmac token signed with the public modulus must not pass
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "x", "exp": time.Now().Add(time.Hour).Unix()}).SignedString(key.N.Bytes())
+	if w := do(newEngine(a), "/me", "Authorization", "Bearer "+forged); w.Code != http.StatusUnauthorized {
+		t.Fatalf("alg confusion: %d", w.Code)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	e := newEngine(NewAPIKey("", NewStaticKeyStore(APIKey{Key: "k-123", Subject: "billing", Roles: []string{"admin"}})))
+	if w := do(e, "/admin", DefaultAPIKeyHeader, "k-123"); w.Code != http.StatusOK {
+		t.Fatalf("valid key: %d", w.Code)
+	}
+	if w := do(e, "/me", DefaultAPIKeyHeader, "nope"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown key: %d", w.Code)
+	}
+}
+
+func TestRedisKeyStoreLazy(t *testing.T) {
+	s := NewRedisKeyStore(nil, "")
+	if s.client.Load() != nil {
+		t.Fatal("redis client resolved at construction")
+	}
+	if s.prefix != DefaultAPIKeyRedisKey {
+		t.Fatalf("prefix: %s", s.prefix)
+	}
+}
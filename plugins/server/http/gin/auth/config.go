@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+)
+
+const (
+	JWT_SECRET_KEY            = "server.auth.jwt.secret"
+	JWT_PUBLIC_KEY_FILE_KEY   = "server.auth.jwt.publicKeyFile"
+	JWT_JWKS_FILE_KEY         = "server.auth.jwt.jwksFile"
+	JWT_JWKS_URL_KEY          = "server.auth.jwt.jwksUrl"
+	JWT_JWKS_CACHE_TTL_KEY    = "server.auth.jwt.jwksCacheTtl"
+	JWT_ISSUER_KEY            = "server.auth.jwt.issuer"
+	JWT_AUDIENCE_KEY          = "server.auth.jwt.audience"
+	JWT_ALGORITHMS_KEY        = "server.auth.jwt.algorithms"
+	JWT_LEEWAY_KEY            = "server.auth.jwt.leeway"
+	JWT_ROLES_CLAIM_KEY       = "server.auth.jwt.rolesClaim"
+	JWT_PERMISSIONS_CLAIM_KEY = "server.auth.jwt.permissionsClaim"
+	APIKEY_HEADER_KEY         = "server.auth.apiKey.header"
+	// APIKEY_STORE_KEY is config (default) or redis
+	APIKEY_STORE_KEY        = "server.auth.apiKey.store"
+	APIKEY_REDIS_PREFIX_KEY = "server.auth.apiKey.redisPrefix"
+	// APIKEYS_KEY maps names to APIKey entries, e.g. server.auth.apiKeys.billing.key=...
+	APIKEYS_KEY      = "server.auth.apiKeys"
+	APIKeyStoreRedis = "redis"
+)
+
+func JWTConfigFromEnv() JWTConfig {
+	cfg := env.GetInstance()
+	return JWTConfig{
+		Secret:           cfg.GetString(JWT_SECRET_KEY),
+		PublicKeyFile:    cfg.GetString(JWT_PUBLIC_KEY_FILE_KEY),
+		JWKSFile:         cfg.GetString(JWT_JWKS_FILE_KEY),
+		JWKSURL:          cfg.GetString(JWT_JWKS_URL_KEY),
+		JWKSCacheTTL:     cfg.GetDuration(JWT_JWKS_CACHE_TTL_KEY),
+		Issuer:           cfg.GetString(JWT_ISSUER_KEY),
+		Audience:         cfg.GetString(JWT_AUDIENCE_KEY),
+		Algorithms:       cfg.GetStringSlice(JWT_ALGORITHMS_KEY),
+		Leeway:           cfg.GetDuration(JWT_LEEWAY_KEY),
+		RolesClaim:       cfg.GetString(JWT_ROLES_CLAIM_KEY),
+		PermissionsClaim: cfg.GetString(JWT_PERMISSIONS_CLAIM_KEY),
+	}
+}
+
+// AuthenticatorsFromEnv builds a JWT authenticator when a key source is configured,
+// followed by an API key authenticator when keys are configured or stored in Redis
+func AuthenticatorsFromEnv() ([]Authenticator, error) {
+	cfg := env.GetInstance()
+	var authenticators []Authenticator
+	jc := JWTConfigFromEnv()
+	if len(jc.Secret) > 0 || len(jc.PublicKeyFile) > 0 || len(jc.JWKSFile) > 0 || len(jc.JWKSURL) > 0 {
+		a, err := NewJWT(jc)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+	header := cfg.GetString(APIKEY_HEADER_KEY)
+	if cfg.GetString(APIKEY_STORE_KEY) == APIKeyStoreRedis {
+		store := NewRedisKeyStore(nil, cfg.GetString(APIKEY_REDIS_PREFIX_KEY))
+		authenticators = append(authenticators, NewAPIKey(header, store))
+	} else {
+		keys := make(map[string]APIKey)
+		if err := cfg.UnmarshalKey(APIKEYS_KEY, &keys); err != nil {
+			return nil, err
+		}
+		if len(keys) > 0 {
+			list := make([]APIKey, 0, len(keys))
+			for name, k := range keys {
+				if len(k.Subject) == 0 {
+					k.Subject = name
+				}
+				list = append(list, k)
+			}
+			authenticators = append(authenticators, NewAPIKey(header, NewStaticKeyStore(list...)))
+		}
+	}
+	return authenticators, nil
+}
+
+// FromEnv is Authenticate with the authenticators configured under server.auth.*, it panics on invalid config
+func FromEnv() gin.HandlerFunc {
+	authenticators, err := AuthenticatorsFromEnv()
+	if err != nil {
+		logger.Panic("[auth] config error:", err.Error())
+	}
+	return Authenticate(authenticators...)
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/skirrund/gcloud/cache/local"
+	"github.com/skirrund/gcloud/logger"
+)
+
+const (
+	DefaultJWKSCacheTTL = 10 * time.Minute
+	// jwksMinRefresh throttles refetches caused by unknown kids
+	jwksMinRefresh = time.Minute
+	jwksTimeout    = 10 * time.Second
+)
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwks struct {
+	file        string
+	url         string
+	cache       local.Cache[string, map[string]any]
+	mu          sync.Mutex
+	lastRefresh time.Time
+}
+
+func newJWKS(file, url string, ttl time.Duration) *jwks {
+	if ttl <= 0 {
+		ttl = DefaultJWKSCacheTTL
+	}
+	c, _ := local.MustBuilder[string, map[string]any](16).WithTTL(ttl).Build()
+	return &jwks{file: file, url: url, cache: c}
+}
+
+func (j *jwks) source() string {
+	if len(j.url) > 0 {
+		return j.url
+	}
+	return j.file
+}
+
+func (j *jwks) key(ctx context.Context, kid string) (any, error) {
+	keys, ok := j.cache.Get(j.source())
+	if !ok {
+		var err error
+		if keys, err = j.refresh(ctx, true); err != nil {
+			return nil, err
+		}
+	}
+	if k, ok := keys[kid]; ok {
+		return k, nil
+	}
+	// the issuer may have rotated its keys since the last fetch
+	keys, err := j.refresh(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if k, ok := keys[kid]; ok {
+		return k, nil
+	}
+	return nil, errors.New("unknown kid " + kid)
+}
+
+func (j *jwks) refresh(ctx context.Context, force bool) (map[string]any, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !force && time.Since(j.lastRefresh) < jwksMinRefresh {
+		if keys, ok := j.cache.Get(j.source()); ok {
+			return keys, nil
+		}
+	}
+	b, err := j.fetch(ctx)
+	if err != nil {
+		logger.Error("[auth] load jwks error:", j.source(), ",", err.Error())
+		return nil, err
+	}
+	keys, err := parseJWKS(b)
+	if err != nil {
+		return nil, err
+	}
+	j.lastRefresh = time.Now()
+	j.cache.Set(j.source(), keys)
+	return keys, nil
+}
+
+func (j *jwks) fetch(ctx context.Context) ([]byte, error) {
+	if len(j.url) == 0 {
+		return os.ReadFile(j.file)
+	}
+	ctx, cancel := context.WithTimeout(ctx, jwksTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("jwks status " + resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func parseJWKS(b []byte) (map[string]any, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		pub, err := k.publicKey()
+		if err != nil {
+			logger.Warn("[auth] skip jwk:", k.Kid, ",", err.Error())
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New("unsupported curve " + k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, errors.New("unsupported curve " + k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, errors.New("unsupported kty " + k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	MethodJWT               = "jwt"
+	DefaultRolesClaim       = "roles"
+	DefaultPermissionsClaim = "permissions"
+	bearerPrefix            = "Bearer "
+)
+
+var (
+	hmacAlgorithms   = []string{"HS256", "HS384", "HS512"}
+	publicAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+)
+
+type JWTConfig struct {
+	// Secret verifies HS256/384/512 tokens
+	Secret string
+	// PublicKeyFile is a PEM public key or certificate verifying RS/PS/ES/EdDSA tokens
+	PublicKeyFile string
+	// JWKSFile and JWKSURL provide keys selected by the token's kid header
+	JWKSFile string
+	JWKSURL  string
+	// JWKSCacheTTL is how long a fetched key set is kept, DefaultJWKSCacheTTL when zero
+	JWKSCacheTTL time.Duration
+	Issuer       string
+	Audience     string
+	// Algorithms defaults to every algorithm the configured keys can verify
+	Algorithms []string
+	Leeway     time.Duration
+	// RolesClaim and PermissionsClaim name the claims holding a list or a space separated string
+	RolesClaim       string
+	PermissionsClaim string
+}
+
+// JWTAuthenticator verifies bearer tokens of the Authorization header
+type JWTAuthenticator struct {
+	cfg       JWTConfig
+	parser    *jwt.Parser
+	secret    []byte
+	publicKey any
+	jwks      *jwks
+}
+
+func NewJWT(cfg JWTConfig) (*JWTAuthenticator, error) {
+	a := &JWTAuthenticator{cfg: cfg}
+	if len(cfg.RolesClaim) == 0 {
+		a.cfg.RolesClaim = DefaultRolesClaim
+	}
+	if len(cfg.PermissionsClaim) == 0 {
+		a.cfg.PermissionsClaim = DefaultPermissionsClaim
+	}
+	algorithms := cfg.Algorithms
+	if len(cfg.Secret) > 0 {
+		a.secret = []byte(cfg.Secret)
+		if len(cfg.Algorithms) == 0 {
+			algorithms = append(algorithms, hmacAlgorithms...)
+		}
+	}
+	if len(cfg.PublicKeyFile) > 0 {
+		key, err := loadPublicKey(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		a.publicKey = key
+	}
+	if len(cfg.JWKSFile) > 0 || len(cfg.JWKSURL) > 0 {
+		a.jwks = newJWKS(cfg.JWKSFile, cfg.JWKSURL, cfg.JWKSCacheTTL)
+	}
+	if (a.publicKey != nil || a.jwks != nil) && len(cfg.Algorithms) == 0 {
+		algorithms = append(algorithms, publicAlgorithms...)
+	}
+	if len(algorithms) == 0 {
+		return nil, errors.New("[auth] jwt needs a secret, a public key or a jwks")
+	}
+	opts := []jwt.ParserOption{jwt.WithValidMethods(algorithms), jwt.WithLeeway(cfg.Leeway), jwt.WithExpirationRequired()}
+	if len(cfg.Issuer) > 0 {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if len(cfg.Audience) > 0 {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	a.parser = jwt.NewParser(opts...)
+	return a, nil
+}
+
+func loadPublicKey(file string) (any, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("[auth] no PEM data in " + file)
+	}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParsePKCS1PublicKey(block.Bytes)
+}
+
+func (a *JWTAuthenticator) keyFunc(ctx context.Context) jwt.Keyfunc {
+	return func(t *jwt.Token) (any, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+			if a.secret == nil {
+				return nil, errors.New("hmac tokens are not accepted")
+			}
+			return a.secret, nil
+		}
+		if kid, _ := t.Header["kid"].(string); len(kid) > 0 && a.jwks != nil {
+			return a.jwks.key(ctx, kid)
+		}
+		if a.publicKey != nil {
+			return a.publicKey, nil
+		}
+		return nil, errors.New("no key for token")
+	}
+}
+
+// Verify checks the signature, expiry, issuer and audience of a raw token
+func (a *JWTAuthenticator) Verify(ctx context.Context, token string) (*Claims, error) {
+	mc := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(token, mc, a.keyFunc(ctx)); err != nil {
+		return nil, err
+	}
+	sub, _ := mc.GetSubject()
+	return &Claims{
+		Subject:     sub,
+		Roles:       stringList(mc[a.cfg.RolesClaim]),
+		Permissions: stringList(mc[a.cfg.PermissionsClaim]),
+		Method:      MethodJWT,
+		Raw:         mc,
+	}, nil
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx *gin.Context) (*Claims, error) {
+	h := ctx.GetHeader("Authorization")
+	if len(h) <= len(bearerPrefix) || !strings.EqualFold(h[:len(bearerPrefix)], bearerPrefix) {
+		return nil, ErrNoCredentials
+	}
+	return a.Verify(ctx.Request.Context(), strings.TrimSpace(h[len(bearerPrefix):]))
+}
+
+func stringList(v any) []string {
+	switch l := v.(type) {
+	case string:
+		return strings.Fields(strings.ReplaceAll(l, ",", " "))
+	case []string:
+		return l
+	case []any:
+		out := make([]string, 0, len(l))
+		for _, s := range l {
+			if str, ok := s.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package gin
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	gm "github.com/skirrund/gcloud/plugins/server/http/gin/middleware"
+	"github.com/skirrund/gcloud/server"
+)
+
+const (
+	COMPRESSION_ENABLED_KEY   = "server.compression.enabled"
+	COMPRESSION_ENCODINGS_KEY = "server.compression.encodings"
+	// COMPRESSION_LEVELS_KEY maps encodings to levels, e.g. server.compression.levels.gzip=5
+	COMPRESSION_LEVELS_KEY        = "server.compression.levels"
+	COMPRESSION_MIN_SIZE_KEY      = "server.compression.minSize"
+	COMPRESSION_CONTENT_TYPES_KEY = "server.compression.contentTypes"
+)
+
+// CompressConfigFromEnv reads server.compression.*
+func CompressConfigFromEnv() (gm.CompressConfig, error) {
+	cfg := env.GetInstance()
+	c := gm.CompressConfig{
+		Encodings: cfg.GetStringSlice(COMPRESSION_ENCODINGS_KEY),
+		MinSize:   cfg.GetInt(COMPRESSION_MIN_SIZE_KEY),
+	}
+	if ct := cfg.GetStringSlice(COMPRESSION_CONTENT_TYPES_KEY); len(ct) > 0 {
+		c.ContentTypes = ct
+	}
+	err := cfg.UnmarshalKey(COMPRESSION_LEVELS_KEY, &c.Levels)
+	return c, err
+}
+
+var (
+	// compressor is the latest middleware built by CompressFromEnv, its config follows server.compression.*
+	compressor       atomic.Pointer[gm.Compressor]
+	compressHookOnce sync.Once
+)
+
+// CompressFromEnv builds the compression middleware from server.compression.* and reloads it on ConfigChangeEvent,
+// only the latest middleware is reloaded
+func CompressFromEnv() gin.HandlerFunc {
+	cfg, err := CompressConfigFromEnv()
+	if err != nil {
+		logger.Error("[GIN] compression config error:", err.Error())
+	}
+	c, err := gm.NewCompressor(cfg)
+	if err != nil {
+		logger.Error("[GIN] compression config error:", err.Error())
+		c, _ = gm.NewCompressor(gm.CompressConfig{})
+	}
+	compressor.Store(c)
+	compressHookOnce.Do(func() {
+		server.RegisterEventHook(server.ConfigChangeEvent, reloadCompression)
+	})
+	return c.Handler()
+}
+
+func reloadCompression(eventType server.EventName, eventInfo any) error {
+	c := compressor.Load()
+	if c == nil {
+		return nil
+	}
+	cfg, err := CompressConfigFromEnv()
+	if err == nil {
+		err = c.Update(cfg)
+	}
+	if err != nil {
+		logger.Error("[GIN] compression reload error:", err.Error())
+	}
+	return err
+}
+
+// registerCompression must run before LoggingMiddleware is installed so the logs keep the uncompressed body
+func registerCompression(s *gin.Engine) {
+	if env.GetInstance().GetBool(COMPRESSION_ENABLED_KEY) {
+		logger.Info("[GIN] compression enabled")
+		s.Use(CompressFromEnv())
+	}
+}
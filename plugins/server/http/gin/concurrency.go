@@ -0,0 +1,63 @@
+package gin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	gm "github.com/skirrund/gcloud/plugins/server/http/gin/middleware"
+	"github.com/skirrund/gcloud/server/concurrency"
+)
+
+const (
+	// CONCURRENCY_ALGORITHM_KEY is one of fixed (default), aimd or gradient
+	CONCURRENCY_ALGORITHM_KEY      = "server.concurrency.algorithm"
+	CONCURRENCY_INITIAL_LIMIT_KEY  = "server.concurrency.initialLimit"
+	CONCURRENCY_MIN_LIMIT_KEY      = "server.concurrency.minLimit"
+	CONCURRENCY_TIMEOUT_KEY        = "server.concurrency.timeout"
+	CONCURRENCY_LOW_RATIO_KEY      = "server.concurrency.lowPriorityRatio"
+	CONCURRENCY_LOW_PRIORITY_PATHS = "server.concurrency.lowPriorityPaths"
+	ConcurrencyAlgorithmFixed      = "fixed"
+	ConcurrencyAlgorithmAIMD       = "aimd"
+	ConcurrencyAlgorithmGradient   = "gradient"
+	DefaultConcurrencyInitialLimit = 100
+	DefaultConcurrencyMinLimit     = 10
+)
+
+// newConcurrencyLimiter builds the limiter for server.Options.Concurrency, which caps the adaptive algorithms
+func newConcurrencyLimiter(max int) *concurrency.Limiter {
+	cfg := env.GetInstance()
+	initial := min(cfg.GetIntWithDefault(CONCURRENCY_INITIAL_LIMIT_KEY, DefaultConcurrencyInitialLimit), max)
+	minLimit := min(cfg.GetIntWithDefault(CONCURRENCY_MIN_LIMIT_KEY, DefaultConcurrencyMinLimit), initial)
+	var algorithm concurrency.Algorithm
+	switch name := cfg.GetStringWithDefault(CONCURRENCY_ALGORITHM_KEY, ConcurrencyAlgorithmFixed); name {
+	case ConcurrencyAlgorithmAIMD:
+		algorithm = concurrency.NewAIMD(initial, minLimit, max, cfg.GetDuration(CONCURRENCY_TIMEOUT_KEY))
+	case ConcurrencyAlgorithmGradient:
+		algorithm = concurrency.NewGradient(initial, minLimit, max)
+	default:
+		algorithm = concurrency.Fixed(max)
+	}
+	l := concurrency.New(algorithm)
+	if r := cfg.GetFloat64(CONCURRENCY_LOW_RATIO_KEY); r > 0 {
+		l.LowRatio = r
+	}
+	return l
+}
+
+func registerConcurrencyLimit(s *gin.Engine, max int) {
+	if max <= 0 {
+		return
+	}
+	classes := map[string]concurrency.Priority{
+		HealthPath:    concurrency.PriorityCritical,
+		LivenessPath:  concurrency.PriorityCritical,
+		ReadinessPath: concurrency.PriorityCritical,
+		"/metrics":    concurrency.PriorityCritical,
+	}
+	for _, p := range env.GetInstance().GetStringSlice(CONCURRENCY_LOW_PRIORITY_PATHS) {
+		classes[p] = concurrency.PriorityLow
+	}
+	l := newConcurrencyLimiter(max)
+	logger.Info("[GIN] concurrency limit:", max, " algorithm:", env.GetInstance().GetStringWithDefault(CONCURRENCY_ALGORITHM_KEY, ConcurrencyAlgorithmFixed))
+	s.Use(gm.ConcurrencyLimit(l, gm.PathPriority(classes)))
+}
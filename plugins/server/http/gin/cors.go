@@ -0,0 +1,96 @@
+package gin
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	gm "github.com/skirrund/gcloud/plugins/server/http/gin/middleware"
+	"github.com/skirrund/gcloud/server"
+)
+
+const (
+	// CORS_KEY holds the server wide policy, installed on the engine when server.cors.enabled is true.
+	// Group policies live under their own prefix, e.g. server.cors.groups.open, see UseCors.
+	CORS_KEY                   = "server.cors"
+	CORS_ENABLED_KEY           = CORS_KEY + ".enabled"
+	corsAllowOriginsSuffix     = ".allowOrigins"
+	corsAllowMethodsSuffix     = ".allowMethods"
+	corsAllowHeadersSuffix     = ".allowHeaders"
+	corsExposeHeadersSuffix    = ".exposeHeaders"
+	corsAllowCredentialsSuffix = ".allowCredentials"
+	corsMaxAgeSuffix           = ".maxAge"
+)
+
+// CorsConfigFromEnv reads a CORS policy stored under prefix
+func CorsConfigFromEnv(prefix string) gm.CorsConfig {
+	cfg := env.GetInstance()
+	return gm.CorsConfig{
+		AllowOrigins:     cfg.GetStringSlice(prefix + corsAllowOriginsSuffix),
+		AllowMethods:     cfg.GetStringSlice(prefix + corsAllowMethodsSuffix),
+		AllowHeaders:     cfg.GetStringSlice(prefix + corsAllowHeadersSuffix),
+		ExposeHeaders:    cfg.GetStringSlice(prefix + corsExposeHeadersSuffix),
+		AllowCredentials: cfg.GetBool(prefix + corsAllowCredentialsSuffix),
+		MaxAge:           cfg.GetDuration(prefix + corsMaxAgeSuffix),
+	}
+}
+
+var (
+	corsMu       sync.Mutex
+	corsHandlers = make(map[string]*gm.CorsHandler)
+	corsHookOnce sync.Once
+)
+
+// CorsFromEnv builds a CORS middleware from the policy under prefix, calls with the same prefix share one policy
+// and every policy is reloaded on ConfigChangeEvent
+func CorsFromEnv(prefix string) gin.HandlerFunc {
+	corsMu.Lock()
+	defer corsMu.Unlock()
+	if c, ok := corsHandlers[prefix]; ok {
+		return c.Handler()
+	}
+	c, err := gm.NewCors(CorsConfigFromEnv(prefix))
+	if err != nil {
+		logger.Error("[GIN] cors config error:", prefix, ",", err.Error())
+		c, _ = gm.NewCors(gm.CorsConfig{})
+	}
+	corsHandlers[prefix] = c
+	corsHookOnce.Do(func() {
+		server.RegisterEventHook(server.ConfigChangeEvent, reloadCors)
+	})
+	return c.Handler()
+}
+
+func reloadCors(eventType server.EventName, eventInfo any) error {
+	corsMu.Lock()
+	handlers := make(map[string]*gm.CorsHandler, len(corsHandlers))
+	for prefix, c := range corsHandlers {
+		handlers[prefix] = c
+	}
+	corsMu.Unlock()
+	var errs []error
+	for prefix, c := range handlers {
+		if err := c.Update(CorsConfigFromEnv(prefix)); err != nil {
+			logger.Error("[GIN] cors reload error:", prefix, ",", err.Error())
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// UseCors installs the policy under prefix on a route group, call it before adding the routes. Preflights are answered by
+// an OPTIONS catch-all on the group since they never match the group's own routes.
+func UseCors(group *gin.RouterGroup, prefix string) {
+	h := CorsFromEnv(prefix)
+	group.Use(h)
+	group.OPTIONS("/*path", h)
+}
+
+func registerCors(s *gin.Engine) {
+	if env.GetInstance().GetBool(CORS_ENABLED_KEY) {
+		logger.Info("[GIN] cors enabled:", CORS_KEY)
+		s.Use(CorsFromEnv(CORS_KEY))
+	}
+}
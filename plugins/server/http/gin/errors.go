@@ -0,0 +1,51 @@
+package gin
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	gm "github.com/skirrund/gcloud/plugins/server/http/gin/middleware"
+	"github.com/skirrund/gcloud/response"
+	"github.com/skirrund/gcloud/server"
+	uValidator "github.com/skirrund/gcloud/utils/validator"
+)
+
+const (
+	// ERRORS_HIDE_INTERNAL_KEY defaults to true for the ProductionProfiles
+	ERRORS_HIDE_INTERNAL_KEY = "server.errors.hideInternal"
+	// ERRORS_STATUS_KEY maps Msginfo codes to HTTP statuses, e.g. server.errors.status.400010=400
+	ERRORS_STATUS_KEY = "server.errors.status"
+)
+
+var ProductionProfiles = []string{"prod", "production", "prd"}
+
+func init() {
+	server.RegisterErrorMatcher(func(err error) (server.ErrorMapping, bool) {
+		var ve validator.ValidationErrors
+		if !errors.As(err, &ve) {
+			return server.ErrorMapping{}, false
+		}
+		return server.ErrorMapping{Msginfo: response.VALIDATE_API_ERROR, Status: http.StatusBadRequest, SubMsg: uValidator.ErrResp(ve)}, true
+	})
+}
+
+func errorHandlerOptions() gm.ErrorHandlerOptions {
+	cfg := env.GetInstance()
+	prod := slices.Contains(ProductionProfiles, strings.ToLower(cfg.GetString(env.SERVER_PROFILE_KEY)))
+	for code, s := range cfg.GetStringMapString(ERRORS_STATUS_KEY) {
+		status, err := strconv.Atoi(s)
+		if err != nil {
+			logger.Error("[GIN] invalid error status:", code, "=", s)
+			continue
+		}
+		// config keys are lower-cased, Msginfo codes are upper case
+		server.SetErrorStatus(strings.ToUpper(code), status)
+	}
+	return gm.ErrorHandlerOptions{HideInternal: cfg.GetBoolWithDefault(ERRORS_HIDE_INTERNAL_KEY, prod)}
+}
@@ -2,7 +2,8 @@ package gin
 
 import (
 	"context"
-	"fmt"
+	"crypto/tls"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,7 +13,9 @@ import (
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/opentracing/opentracing-go"
 	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/plugins/server/http/gin/admin"
 	gm "github.com/skirrund/gcloud/plugins/server/http/gin/middleware"
 	"github.com/skirrund/gcloud/plugins/server/http/gin/prometheus"
 	"github.com/skirrund/gcloud/response"
@@ -20,6 +23,7 @@ import (
 	"github.com/skirrund/gcloud/server/http/cookie"
 	"github.com/skirrund/gcloud/tracer"
 	uValidator "github.com/skirrund/gcloud/utils/validator"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 
@@ -31,15 +35,22 @@ import (
 )
 
 const (
-	CookieDeleteMe            = "DeleteMe"
-	CookieDeleteMaxAge        = 0
-	CookieDeleteVal           = ""
-	DefaultMaxRequestBodySize = 104857600 // 100MB
+	CookieDeleteMe              = "DeleteMe"
+	CookieDeleteMaxAge          = 0
+	CookieDeleteVal             = ""
+	DefaultMaxRequestBodySize   = 104857600 // 100MB
+	DefaultReadTimeout          = 4 * time.Minute
+	DefaultWriteTimeout         = 4 * time.Minute
+	DefaultShutdownTimeout      = 5 * time.Second
+	DefaultH2IdleTimeout        = 15 * time.Second
+	DefaultMaxConcurrentStreams = 256
 )
 
 type Server struct {
-	Srv     *gin.Engine
-	Options server.Options
+	Srv        *gin.Engine
+	Options    server.Options
+	onStarted  []func()
+	onShutdown []func()
 }
 
 func NewServer(options server.Options, routerProvider func(engine *gin.Engine), middleware ...gin.HandlerFunc) server.Server {
@@ -50,27 +61,41 @@ func NewServer(options server.Options, routerProvider func(engine *gin.Engine),
 	// if options.H2C {
 	// 	s.UseH2C = true
 	// }
+	// ErrorHandler recovers the handlers, this catches panics of the middlewares installed above it
 	s.Use(gin.CustomRecovery(func(c *gin.Context, recovered any) {
 		logger.Error("[GIN] recover:", recovered, "\n", string(debug.Stack()))
-
-		c.JSON(200, response.Fail[any](fmt.Sprintf("%v", recovered)))
-		//		c.AbortWithStatus(http.StatusInternalServerError)
+		if !c.Writer.Written() {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, response.CreateMsgInfo[any](response.EXCEPTION, ""))
+		}
 	}))
 	//s.Use(cors)
-	//s.Use(zipkinMiddleware)
-	//zipkin.InitZipkinTracer(s)
-	gp := prometheus.New(s)
+	gp := prometheus.New(s, prometheusOptions()...)
 	s.Use(gp.Middleware())
-	s.Use(gm.TraceMiddleware, gm.LoggingMiddleware)
+	maxBodySize := options.MaxRequestBodySize
+	if maxBodySize == 0 {
+		maxBodySize = DefaultMaxRequestBodySize
+	}
+	s.Use(gm.TraceMiddleware)
+	registerZipkin(s)
+	registerCompression(s)
+	// errors are answered inside logging and metrics so both see the final status
+	s.Use(gm.LoggingMiddleware, gm.ErrorHandler(errorHandlerOptions()), gm.BodyLimit(int64(maxBodySize)))
+	registerCors(s)
+	registerConcurrencyLimit(s, options.Concurrency)
 	if len(middleware) > 0 {
 		s.Use(middleware...)
 	}
+	// limiter and idempotency keys are scoped by the user the auth middleware above sets
+	registerRateLimit(s)
+	registerIdempotency(s)
 	// metrics采样
 	s.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	registerHealth(s)
 	//s.Use(sentinelMiddleware)
-	//initSwagger(s)
+	registerOpenAPI(s)
 
 	pprof.Register(s)
+	admin.Register(s)
 	routerProvider(s)
 	srv.Srv = s
 	return srv
@@ -83,41 +108,70 @@ func (server *Server) GetServeServer() any {
 	return server.Srv
 }
 
+// OnStarted registers hooks that run once the listener is accepting connections
+func (server *Server) OnStarted(f ...func()) {
+	server.onStarted = append(server.onStarted, f...)
+}
+
+// OnShutdown registers hooks that run after the shutdown signal and before connections are drained
+func (server *Server) OnShutdown(f ...func()) {
+	server.onShutdown = append(server.onShutdown, f...)
+}
+
 func (server *Server) Run(graceful ...func()) {
-	srv := &http.Server{
-		Addr:         server.Options.Address,
-		Handler:      server.Srv.Handler(),
-		ReadTimeout:  4 * time.Minute,
-		WriteTimeout: 4 * time.Minute,
-	}
-	if server.Options.H2C {
-		h2s := &http2.Server{}
-		if server.Options.MaxConcurrentStreams > 0 {
-			h2s.MaxConcurrentStreams = server.Options.MaxConcurrentStreams
+	opts := server.Options
+	srv, h2s := newHTTPServer(opts, server.Srv.Handler())
+	tlsEnabled := opts.TLS.Enabled()
+	if tlsEnabled {
+		reloader, err := newCertReloader(opts.TLS.CertFile, opts.TLS.KeyFile)
+		if err != nil {
+			logger.Panic("[GIN] load certificate:", err.Error())
+		}
+		defer reloader.watch()()
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+		if opts.H2 {
+			if err := http2.ConfigureServer(srv, h2s); err != nil {
+				logger.Panic("[GIN] configure http2:", err.Error())
+			}
 		} else {
-			h2s.MaxConcurrentStreams = 256
+			// a non-nil empty map disables the automatic h2 upgrade
+			srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
 		}
-		h2s.IdleTimeout = 15 * time.Second
+	} else if opts.H2C {
 		srv.Handler = h2c.NewHandler(server.Srv, h2s)
 	}
-
-	bodySize := server.Options.MaxRequestBodySize
-	if bodySize > 0 {
-		srv.MaxHeaderBytes = bodySize
-	} else {
-		srv.MaxHeaderBytes = DefaultMaxRequestBodySize
+	logger.Info("[GIN] server starting on:", opts.Address, " tls:", tlsEnabled, " h2:", opts.H2, " h2c:", opts.H2C && !tlsEnabled)
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		logger.Panic("[GIN] listen:", err.Error())
 	}
 	go func() {
-		logger.Info("[GIN] server starting on:", server.Options.Address, " h2c:", server.Options.H2C)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Panic("[GIN] listen:", err.Error())
+		var err error
+		if tlsEnabled {
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			err = srv.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			logger.Panic("[GIN] serve:", err.Error())
+		}
+	}()
+	go func() {
+		for _, f := range server.onStarted {
+			f()
 		}
 	}()
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	logger.Info("[GIN]Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	for _, f := range server.onShutdown {
+		f()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), durationOr(opts.ShutdownTimeout, DefaultShutdownTimeout))
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
 		grace(server, graceful...)
@@ -128,6 +182,34 @@ func (server *Server) Run(graceful ...func()) {
 	logger.Info("[GIN]server has been shutdown")
 }
 
+// newHTTPServer applies the timeouts and limits of opts, zero timeouts take the defaults
+func newHTTPServer(opts server.Options, handler http.Handler) (*http.Server, *http2.Server) {
+	srv := &http.Server{
+		Addr:              opts.Address,
+		Handler:           handler,
+		ReadTimeout:       durationOr(opts.ReadTimeout, DefaultReadTimeout),
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		WriteTimeout:      durationOr(opts.WriteTimeout, DefaultWriteTimeout),
+		IdleTimeout:       opts.IdleTimeout,
+		MaxHeaderBytes:    opts.MaxHeaderBytes,
+	}
+	h2s := &http2.Server{
+		MaxConcurrentStreams: opts.MaxConcurrentStreams,
+		IdleTimeout:          durationOr(opts.IdleTimeout, DefaultH2IdleTimeout),
+	}
+	if h2s.MaxConcurrentStreams == 0 {
+		h2s.MaxConcurrentStreams = DefaultMaxConcurrentStreams
+	}
+	return srv, h2s
+}
+
+func durationOr(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
 func grace(server *Server, g ...func()) {
 	server.Shutdown()
 	if len(g) > 0 {
@@ -149,9 +231,15 @@ func InitTrans(locale string, validate binding.StructValidator) (err error) {
 	return
 }
 
+// GetTraceContext returns a background context with the trace id and the span of the request,
+// it outlives the request so it may be handed to goroutines
 func GetTraceContext(ctx *gin.Context) context.Context {
 	id := ctx.GetString(tracer.TraceIDKey)
-	return tracer.NewContextFromTraceId(id)
+	c := tracer.NewContextFromTraceId(id)
+	if span := opentracing.SpanFromContext(ctx.Request.Context()); span != nil {
+		c = opentracing.ContextWithSpan(c, span)
+	}
+	return trace.ContextWithSpanContext(c, trace.SpanContextFromContext(ctx.Request.Context()))
 }
 
 func GetCookie(name string, ctx *gin.Context) string {
@@ -0,0 +1,43 @@
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/bootstrap/env"
+	gm "github.com/skirrund/gcloud/plugins/server/http/gin/middleware"
+	"github.com/skirrund/gcloud/server"
+)
+
+func TestNewServerRateLimitByUser(t *testing.T) {
+	cfg := env.GetInstance()
+	cfg.Set(RATELIMIT_ENABLED_KEY, true)
+	cfg.Set(RATELIMIT_RULES_KEY, map[string]any{
+		"order": map[string]any{"path": "/order", "keyBy": gm.KeyByUser, "events": 1, "period": "1m"},
+	})
+	t.Cleanup(func() {
+		cfg.Set(RATELIMIT_ENABLED_KEY, false)
+		cfg.Set(RATELIMIT_RULES_KEY, nil)
+	})
+	auth := func(ctx *gin.Context) {
+		if user := ctx.GetHeader("X-User"); len(user) > 0 {
+			ctx.Set(gm.UserIDKey, user)
+		}
+	}
+	srv := NewServer(server.Options{}, func(e *gin.Engine) {
+		e.GET("/order", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	}, auth)
+	e := srv.(*Server).Srv
+	get := func(user string) int {
+		req := httptest.NewRequest(http.MethodGet, "/order", nil)
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w.Code
+	}
+	if a, b, again := get("a"), get("b"), get("a"); a != http.StatusOK || b != http.StatusOK || again != http.StatusTooManyRequests {
+		t.Fatalf("user a %d, user b %d, user a again %d", a, b, again)
+	}
+}
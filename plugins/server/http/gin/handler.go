@@ -0,0 +1,164 @@
+package gin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	gm "github.com/skirrund/gcloud/plugins/server/http/gin/middleware"
+	"github.com/skirrund/gcloud/plugins/server/http/gin/openapi"
+	"github.com/skirrund/gcloud/response"
+	"github.com/skirrund/gcloud/server"
+	"github.com/skirrund/gcloud/tracer"
+	uValidator "github.com/skirrund/gcloud/utils/validator"
+)
+
+// BindError is a request that could not be decoded, it is answered with VALIDATE_API_ERROR
+type BindError struct {
+	Err error
+}
+
+func (e *BindError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+func init() {
+	server.RegisterErrorMatcher(func(err error) (server.ErrorMapping, bool) {
+		var be *BindError
+		if !errors.As(err, &be) {
+			return server.ErrorMapping{}, false
+		}
+		return server.ErrorMapping{Msginfo: response.VALIDATE_API_ERROR, Status: http.StatusBadRequest, SubMsg: be.Error()}, true
+	})
+}
+
+type ginCtxKey struct{}
+
+// GinContext returns the *gin.Context behind the context handed to a Handle function,
+// e.g. to set cookies or response headers
+func GinContext(ctx context.Context) (*gin.Context, bool) {
+	gc, ok := ctx.Value(ginCtxKey{}).(*gin.Context)
+	return gc, ok
+}
+
+// HandlerContext is the request context carrying the trace id of GetTraceContext,
+// it is cancelled when the client goes away
+func HandlerContext(ctx *gin.Context) context.Context {
+	c := tracer.WithContext(ctx.Request.Context(), ctx.GetString(tracer.TraceIDKey))
+	return context.WithValue(c, ginCtxKey{}, ctx)
+}
+
+// Bind allocates a Req, fills it from the body, query, form, uri and header and validates it once
+// every source is bound. Decoding failures are returned as *BindError, validation failures as
+// validator.ValidationErrors.
+func Bind[Req any](ctx *gin.Context) (Req, error) {
+	var req Req
+	target := any(&req)
+	if t := reflect.TypeFor[Req](); t.Kind() == reflect.Pointer {
+		// bind into a fresh value rather than through a nil pointer
+		v := reflect.New(t.Elem())
+		req = v.Interface().(Req)
+		target = req
+	}
+	if err := bindAll(ctx, target); err != nil {
+		return req, &BindError{Err: err}
+	}
+	if binding.Validator != nil {
+		if err := binding.Validator.ValidateStruct(target); err != nil {
+			return req, err
+		}
+	}
+	if err := uValidator.ValidateStruct(target); err != nil {
+		return req, err
+	}
+	return req, nil
+}
+
+// bindAll binds every source like ShouldBind, uri tags included. The gin bindings validate
+// as they go, before later sources are bound, so their validation errors are left to Bind.
+func bindAll(ctx *gin.Context, obj any) error {
+	var tagHeader, tagQuery, tagPath bool
+	if t := reflect.TypeOf(obj).Elem(); t.Kind() == reflect.Struct {
+		for i := range t.NumField() {
+			tag := t.Field(i).Tag
+			tagHeader = tagHeader || len(tag.Get("header")) > 0
+			tagQuery = tagQuery || len(tag.Get("query")) > 0 || len(tag.Get("form")) > 0
+			tagPath = tagPath || len(tag.Get("uri")) > 0 || len(tag.Get("path")) > 0
+		}
+	}
+	binds := []func() error{func() error { return ctx.ShouldBind(obj) }}
+	if tagHeader {
+		binds = append(binds, func() error { return ctx.ShouldBindHeader(obj) })
+	}
+	if tagQuery {
+		binds = append(binds,
+			func() error { return ctx.ShouldBindWith(obj, binding.Query) },
+			func() error { return ctx.ShouldBindWith(obj, binding.Form) })
+	}
+	if tagPath {
+		binds = append(binds, func() error { return ctx.ShouldBindUri(obj) })
+	}
+	for _, bind := range binds {
+		if err := bind(); err != nil && !validationError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func validationError(err error) bool {
+	var ve validator.ValidationErrors
+	var se binding.SliceValidationError
+	return errors.As(err, &ve) || errors.As(err, &se)
+}
+
+// Handle adapts fn to a gin handler: the request is bound and validated, the result is sent
+// as response.Response[Resp] and errors go to the ErrorHandler middleware, which maps them
+// through the server error registry
+func Handle[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		req, err := Bind[Req](ctx)
+		if err != nil {
+			gm.AbortWithError(ctx, err)
+			return
+		}
+		resp, err := fn(HandlerContext(ctx), req)
+		if err != nil {
+			gm.AbortWithError(ctx, err)
+			return
+		}
+		SendJSON(ctx, response.Success(resp))
+	}
+}
+
+// HandleNoRequest is Handle for handlers without input
+func HandleNoRequest[Resp any](fn func(ctx context.Context) (Resp, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		resp, err := fn(HandlerContext(ctx))
+		if err != nil {
+			gm.AbortWithError(ctx, err)
+			return
+		}
+		SendJSON(ctx, response.Success(resp))
+	}
+}
+
+// HandleRoute registers fn on group with Handle and describes it for the OpenAPI document,
+// Request and Response of r default to Req and response.Response[Resp]
+func HandleRoute[Req, Resp any](group *gin.RouterGroup, method, path string, r openapi.Route, fn func(ctx context.Context, req Req) (Resp, error)) gin.IRoutes {
+	if r.Request == nil {
+		r.Request = *new(Req)
+	}
+	if r.Response == nil {
+		r.Response = response.Response[Resp]{}
+	}
+	return openapi.Handle(group, method, path, r, Handle(fn))
+}
@@ -0,0 +1,62 @@
+package gin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	gm "github.com/skirrund/gcloud/plugins/server/http/gin/middleware"
+	"github.com/skirrund/gcloud/response"
+	"github.com/skirrund/gcloud/tracer"
+)
+
+type greetReq struct {
+	ID   int    `uri:"id" binding:"required"`
+	Name string `json:"name" binding:"required"`
+	Lang string `header:"X-Lang" binding:"required"`
+}
+
+func TestHandle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(gm.TraceMiddleware, gm.ErrorHandler(gm.ErrorHandlerOptions{}))
+	e.POST("/greet/:id", Handle(func(ctx context.Context, req *greetReq) (string, error) {
+		if tracer.GetTraceID(ctx) == nil {
+			return "", errors.New("no trace id")
+		}
+		if _, ok := GinContext(ctx); !ok {
+			return "", errors.New("no gin context")
+		}
+		if req.Name == "boom" {
+			return "", errors.New("boom")
+		}
+		return req.Lang + ":" + req.Name + ":" + string(rune('0'+req.ID)), nil
+	}))
+	do := func(body string) (int, response.Response[string]) {
+		req := httptest.NewRequest(http.MethodPost, "/greet/7", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Lang", "en")
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		var resp response.Response[string]
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+	if code, resp := do(`{"name":"gcloud"}`); code != http.StatusOK || resp.Result != "en:gcloud:7" || !resp.Success {
+		t.Errorf("ok: %d %+v", code, resp)
+	}
+	if code, resp := do(`{}`); code != http.StatusBadRequest || resp.Code != response.VALIDATE_API_ERROR.Code {
+		t.Errorf("validation: %d %+v", code, resp)
+	}
+	if code, resp := do(`{`); code != http.StatusBadRequest || resp.Code != response.VALIDATE_API_ERROR.Code {
+		t.Errorf("malformed: %d %+v", code, resp)
+	}
+	if code, resp := do(`{"name":"boom"}`); code != http.StatusInternalServerError || resp.Code != response.EXCEPTION.Code {
+		t.Errorf("error: %d %+v", code, resp)
+	}
+}
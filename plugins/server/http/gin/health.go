@@ -0,0 +1,38 @@
+package gin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/server/health"
+)
+
+const (
+	HealthPath    = "/health"
+	LivenessPath  = "/health/liveness"
+	ReadinessPath = "/health/readiness"
+)
+
+// HealthHandler reports the probes of the given kind, answering 503 when any of them is down
+func HealthHandler(kind health.Kind) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var report health.Report
+		if kind == health.KindLiveness {
+			report = health.Liveness(ctx.Request.Context())
+		} else {
+			report = health.Readiness(ctx.Request.Context())
+		}
+		status := http.StatusOK
+		if report.Status != health.StatusUp {
+			status = http.StatusServiceUnavailable
+		}
+		ctx.JSON(status, report)
+	}
+}
+
+func registerHealth(s *gin.Engine) {
+	readiness := HealthHandler(health.KindReadiness)
+	s.GET(HealthPath, readiness)
+	s.GET(ReadinessPath, readiness)
+	s.GET(LivenessPath, HealthHandler(health.KindLiveness))
+}
@@ -0,0 +1,43 @@
+package gin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	gm "github.com/skirrund/gcloud/plugins/server/http/gin/middleware"
+)
+
+const (
+	IDEMPOTENCY_ENABLED_KEY  = "server.idempotency.enabled"
+	IDEMPOTENCY_HEADER_KEY   = "server.idempotency.header"
+	IDEMPOTENCY_PREFIX_KEY   = "server.idempotency.prefix"
+	IDEMPOTENCY_LOCK_TTL_KEY = "server.idempotency.lockTtl"
+	IDEMPOTENCY_TTL_KEY      = "server.idempotency.ttl"
+	IDEMPOTENCY_METHODS_KEY  = "server.idempotency.methods"
+	IDEMPOTENCY_PATHS_KEY    = "server.idempotency.paths"
+	IDEMPOTENCY_REQUIRED_KEY = "server.idempotency.required"
+)
+
+// IdempotencyConfigFromEnv reads server.idempotency.* backed by the default redis client,
+// which is resolved on the first request
+func IdempotencyConfigFromEnv() gm.IdempotencyConfig {
+	cfg := env.GetInstance()
+	return gm.IdempotencyConfig{
+		Store:    &redisIdempotencyStore{},
+		Header:   cfg.GetString(IDEMPOTENCY_HEADER_KEY),
+		Prefix:   cfg.GetString(IDEMPOTENCY_PREFIX_KEY),
+		LockTTL:  cfg.GetDuration(IDEMPOTENCY_LOCK_TTL_KEY),
+		TTL:      cfg.GetDuration(IDEMPOTENCY_TTL_KEY),
+		Methods:  cfg.GetStringSlice(IDEMPOTENCY_METHODS_KEY),
+		Paths:    cfg.GetStringSlice(IDEMPOTENCY_PATHS_KEY),
+		Required: cfg.GetBool(IDEMPOTENCY_REQUIRED_KEY),
+	}
+}
+
+func registerIdempotency(s *gin.Engine) {
+	if env.GetInstance().GetBool(IDEMPOTENCY_ENABLED_KEY) {
+		c := IdempotencyConfigFromEnv()
+		logger.Info("[GIN] idempotency enabled:", c.Paths)
+		s.Use(gm.Idempotency(c))
+	}
+}
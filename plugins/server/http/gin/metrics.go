@@ -0,0 +1,38 @@
+package gin
+
+import (
+	"strconv"
+
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/plugins/server/http/gin/prometheus"
+)
+
+const (
+	METRICS_NAMESPACE_KEY = "server.metrics.namespace"
+	// METRICS_BUCKETS_KEY lists the latency buckets in seconds, e.g. 0.01,0.05,0.1,0.5,1
+	METRICS_BUCKETS_KEY      = "server.metrics.buckets"
+	METRICS_SIZE_BUCKETS_KEY = "server.metrics.sizeBuckets"
+)
+
+func floatsFromEnv(key string) []float64 {
+	var fs []float64
+	for _, s := range env.GetInstance().GetStringSlice(key) {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			logger.Error("[GIN] invalid metrics bucket:", key, "=", s)
+			return nil
+		}
+		fs = append(fs, f)
+	}
+	return fs
+}
+
+func prometheusOptions() []prometheus.Option {
+	return []prometheus.Option{
+		prometheus.Ignore(HealthPath, LivenessPath, ReadinessPath),
+		prometheus.Namespace(env.GetInstance().GetString(METRICS_NAMESPACE_KEY)),
+		prometheus.Buckets(floatsFromEnv(METRICS_BUCKETS_KEY)...),
+		prometheus.SizeBuckets(floatsFromEnv(METRICS_SIZE_BUCKETS_KEY)...),
+	}
+}
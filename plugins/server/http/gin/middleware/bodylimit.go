@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/response"
+)
+
+// BodyLimit rejects requests whose body is larger than max bytes with 413.
+// A declared Content-Length is checked up front, chunked bodies fail on the read past the limit.
+func BodyLimit(max int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if max <= 0 || ctx.Request.Body == nil || ctx.Request.Body == http.NoBody {
+			ctx.Next()
+			return
+		}
+		if ctx.Request.ContentLength > max {
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, response.CreateMsgInfo[any](response.VALIDATE_ERROR, "request body too large"))
+			return
+		}
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, max)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,392 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+	EncodingZstd    = "zstd"
+	EncodingBrotli  = "br"
+
+	// DefaultCompressMinSize is the smallest body worth compressing
+	DefaultCompressMinSize = 1024
+)
+
+var (
+	// DefaultCompressEncodings are in order of server preference, brotli is opt-in since it is the slowest to encode
+	DefaultCompressEncodings = []string{EncodingZstd, EncodingGzip, EncodingDeflate}
+	// DefaultCompressContentTypes are compressed; a trailing "/" matches the whole type and a leading "+" a structured suffix
+	DefaultCompressContentTypes = []string{
+		"text/",
+		"application/json",
+		"application/javascript",
+		"application/xml",
+		"application/x-ndjson",
+		"image/svg+xml",
+		"+json",
+		"+xml",
+	}
+)
+
+// Encoder is a streaming compressor that can be reused through Reset
+type Encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// EncoderFactory creates an Encoder, level 0 asks for the encoding's default level
+type EncoderFactory func(level int) (Encoder, error)
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]EncoderFactory{
+		EncodingGzip: func(level int) (Encoder, error) {
+			if level == 0 {
+				level = gzip.DefaultCompression
+			}
+			return gzip.NewWriterLevel(nil, level)
+		},
+		EncodingDeflate: func(level int) (Encoder, error) {
+			if level == 0 {
+				level = flate.DefaultCompression
+			}
+			return flate.NewWriter(nil, level)
+		},
+		EncodingZstd: func(level int) (Encoder, error) {
+			// browsers refuse windows above 8MB
+			opts := []zstd.EOption{zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(8 << 20)}
+			if level != 0 {
+				opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+			}
+			return zstd.NewWriter(nil, opts...)
+		},
+		EncodingBrotli: func(level int) (Encoder, error) {
+			if level == 0 {
+				level = 4
+			}
+			return brotli.NewWriterLevel(nil, level), nil
+		},
+	}
+)
+
+// RegisterEncoder adds or replaces the encoder of a content-coding token
+func RegisterEncoder(name string, f EncoderFactory) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[strings.ToLower(name)] = f
+}
+
+func encoderFactory(name string) (EncoderFactory, bool) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	f, ok := encoders[name]
+	return f, ok
+}
+
+type CompressConfig struct {
+	// Encodings are offered in order of preference, DefaultCompressEncodings when empty
+	Encodings []string
+	// Levels are keyed by encoding, missing ones use the encoder default
+	Levels map[string]int
+	// MinSize defaults to DefaultCompressMinSize, smaller bodies are sent as is
+	MinSize int
+	// ContentTypes replaces DefaultCompressContentTypes when not nil
+	ContentTypes []string
+}
+
+type compressPolicy struct {
+	encodings    []string
+	pools        map[string]*sync.Pool
+	minSize      int
+	contentTypes []string
+}
+
+// Compressor negotiates the response encoding from Accept-Encoding, the config can be swapped at runtime with Update.
+// Install it before LoggingMiddleware so the logged response body is the uncompressed one.
+type Compressor struct {
+	policy atomic.Pointer[compressPolicy]
+}
+
+func NewCompressor(cfg CompressConfig) (*Compressor, error) {
+	c := &Compressor{}
+	if err := c.Update(cfg); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Compressor) Update(cfg CompressConfig) error {
+	p := &compressPolicy{
+		pools:        make(map[string]*sync.Pool),
+		minSize:      cfg.MinSize,
+		contentTypes: cfg.ContentTypes,
+	}
+	if p.minSize <= 0 {
+		p.minSize = DefaultCompressMinSize
+	}
+	if p.contentTypes == nil {
+		p.contentTypes = DefaultCompressContentTypes
+	}
+	encs := cfg.Encodings
+	if len(encs) == 0 {
+		encs = DefaultCompressEncodings
+	}
+	levels := make(map[string]int, len(cfg.Levels))
+	for k, v := range cfg.Levels {
+		levels[strings.ToLower(k)] = v
+	}
+	for _, name := range encs {
+		name = strings.ToLower(strings.TrimSpace(name))
+		f, ok := encoderFactory(name)
+		if !ok {
+			return fmt.Errorf("unknown encoding: %s", name)
+		}
+		level := levels[name]
+		// the first encoder validates the level and seeds the pool
+		first, err := f(level)
+		if err != nil {
+			return fmt.Errorf("encoding %s: %w", name, err)
+		}
+		pool := &sync.Pool{New: func() any {
+			e, _ := f(level)
+			return e
+		}}
+		pool.Put(first)
+		p.pools[name] = pool
+		p.encodings = append(p.encodings, name)
+	}
+	c.policy.Store(p)
+	return nil
+}
+
+func (p *compressPolicy) allowContentType(ct string) bool {
+	if mt, _, err := mime.ParseMediaType(ct); err == nil {
+		ct = mt
+	}
+	ct = strings.ToLower(ct)
+	// event streams are flushed per event, compressing them only delays delivery
+	if ct == "text/event-stream" {
+		return false
+	}
+	for _, s := range p.contentTypes {
+		switch {
+		case strings.HasSuffix(s, "/"):
+			if strings.HasPrefix(ct, s) {
+				return true
+			}
+		case strings.HasPrefix(s, "+"):
+			if strings.HasSuffix(ct, s) {
+				return true
+			}
+		case ct == s:
+			return true
+		}
+	}
+	return false
+}
+
+// negotiate picks the supported encoding with the highest q-value, ties go to the server preference
+func (p *compressPolicy) negotiate(acceptEncoding string) string {
+	if len(acceptEncoding) == 0 {
+		return ""
+	}
+	qs := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		switch name {
+		case "*":
+			wildcard = q
+		case "x-gzip":
+			qs[EncodingGzip] = q
+		default:
+			qs[name] = q
+		}
+	}
+	best, bestQ := "", 0.0
+	for _, e := range p.encodings {
+		q, ok := qs[e]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best
+}
+
+// Handler wraps the response writer when the client accepts one of the configured encodings
+func (c *Compressor) Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		p := c.policy.Load()
+		if ctx.Request.Method == http.MethodHead || len(ctx.GetHeader("Range")) > 0 {
+			ctx.Next()
+			return
+		}
+		encoding := p.negotiate(ctx.GetHeader("Accept-Encoding"))
+		if len(encoding) == 0 {
+			ctx.Next()
+			return
+		}
+		cw := &compressWriter{ResponseWriter: ctx.Writer, policy: p, encoding: encoding}
+		ctx.Writer = cw
+		defer func() {
+			cw.close()
+			ctx.Writer = cw.ResponseWriter
+		}()
+		ctx.Next()
+	}
+}
+
+// compressWriter holds back the first MinSize bytes before deciding whether to compress,
+// once decided every write goes either through the encoder or straight to the client
+type compressWriter struct {
+	gin.ResponseWriter
+	policy   *compressPolicy
+	encoding string
+	enc      Encoder
+	pending  []byte
+	decided  bool
+	// wrote is set by the first accepted byte, the encoder may still buffer it
+	wrote bool
+}
+
+// eligible is checked on the first write, when the handler has set its headers
+func (w *compressWriter) eligible() bool {
+	status := w.ResponseWriter.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusPartialContent || status == http.StatusNotModified {
+		return false
+	}
+	h := w.Header()
+	if len(h.Get("Content-Encoding")) > 0 {
+		return false
+	}
+	ct := h.Get("Content-Type")
+	return len(ct) == 0 || w.policy.allowContentType(ct)
+}
+
+func (w *compressWriter) decide(compress bool) {
+	w.decided = true
+	h := w.Header()
+	if len(h.Get("Content-Type")) == 0 && len(w.pending) > 0 {
+		// sniff before compression hides the content from net/http
+		h.Set("Content-Type", http.DetectContentType(w.pending))
+	}
+	if ct := h.Get("Content-Type"); len(ct) > 0 && w.policy.allowContentType(ct) && len(h.Get("Content-Encoding")) == 0 {
+		// the representation depends on Accept-Encoding even when this one is too small to compress
+		h.Add("Vary", "Accept-Encoding")
+	} else {
+		compress = false
+	}
+	if compress {
+		pool := w.policy.pools[w.encoding]
+		if enc, ok := pool.Get().(Encoder); ok && enc != nil {
+			h.Del("Content-Length")
+			h.Set("Content-Encoding", w.encoding)
+			if etag := h.Get("ETag"); len(etag) > 0 && !strings.HasPrefix(etag, "W/") {
+				h.Set("ETag", "W/"+etag)
+			}
+			enc.Reset(w.ResponseWriter)
+			w.enc = enc
+		}
+	}
+	if len(w.pending) > 0 {
+		if w.enc != nil {
+			w.enc.Write(w.pending)
+		} else {
+			w.ResponseWriter.Write(w.pending)
+		}
+		w.pending = nil
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if len(b) > 0 {
+		w.wrote = true
+	}
+	if !w.decided {
+		if len(w.pending) == 0 && !w.eligible() {
+			w.decide(false)
+		} else {
+			w.pending = append(w.pending, b...)
+			if len(w.pending) >= w.policy.minSize {
+				w.decide(true)
+			}
+			return len(b), nil
+		}
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Written reports held back and encoded bytes as written so error handlers do not append to a partial body
+func (w *compressWriter) Written() bool {
+	return w.wrote || w.ResponseWriter.Written()
+}
+
+// Flush sends what has been written so far, a stream flushed before reaching MinSize is not compressed
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(len(w.pending) >= w.policy.minSize)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		w.enc.Reset(io.Discard)
+		w.policy.pools[w.encoding].Put(w.enc)
+		w.enc = nil
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/response"
+	"github.com/skirrund/gcloud/server/concurrency"
+)
+
+// PathPriority classifies requests by route pattern or URL path, unknown paths are PriorityNormal
+func PathPriority(classes map[string]concurrency.Priority) func(*gin.Context) concurrency.Priority {
+	return func(ctx *gin.Context) concurrency.Priority {
+		if p, ok := classes[ctx.FullPath()]; ok {
+			return p
+		}
+		if p, ok := classes[ctx.Request.URL.Path]; ok {
+			return p
+		}
+		return concurrency.PriorityNormal
+	}
+}
+
+// ConcurrencyLimit sheds requests over the limit with 503 and SYSTEM_BLOCK_EXCEPTION.
+// Requests that panic, time out or are answered with 503 are reported to the limiter as dropped.
+func ConcurrencyLimit(l *concurrency.Limiter, classify func(*gin.Context) concurrency.Priority) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		release, ok := l.Acquire(classify(ctx))
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, response.CreateMsgInfo[any](response.SYSTEM_BLOCK_EXCEPTION, ""))
+			return
+		}
+		// the panic is recovered further up, the slot must be released on the way
+		panicked := true
+		defer func() {
+			release(panicked || ctx.Writer.Status() == http.StatusServiceUnavailable || errors.Is(ctx.Request.Context().Err(), context.DeadlineExceeded))
+		}()
+		ctx.Next()
+		panicked = false
+	}
+}
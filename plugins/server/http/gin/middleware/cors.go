@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CorsRegexPrefix marks an allowed origin as a regular expression, e.g. "regex:^https://.+\.example\.com$"
+const CorsRegexPrefix = "regex:"
+
+var DefaultCorsMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead, http.MethodOptions}
+
+type CorsConfig struct {
+	// AllowOrigins holds exact origins ("https://a.com"), wildcard subdomains ("https://*.a.com"),
+	// regular expressions prefixed with CorsRegexPrefix, or "*" for any origin
+	AllowOrigins []string
+	// AllowMethods defaults to DefaultCorsMethods
+	AllowMethods []string
+	// AllowHeaders may contain "*" to accept whatever the preflight asks for
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type corsPolicy struct {
+	anyOrigin     bool
+	exact         map[string]struct{}
+	patterns      []*regexp.Regexp
+	methods       map[string]struct{}
+	allowMethods  string
+	anyHeader     bool
+	allowHeaders  string
+	exposeHeaders string
+	credentials   bool
+	maxAge        string
+}
+
+// CorsHandler applies a CorsConfig, the config can be swapped at runtime with Update
+type CorsHandler struct {
+	policy atomic.Pointer[corsPolicy]
+}
+
+func NewCors(cfg CorsConfig) (*CorsHandler, error) {
+	c := &CorsHandler{}
+	if err := c.Update(cfg); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *CorsHandler) Update(cfg CorsConfig) error {
+	p := &corsPolicy{
+		exact:       make(map[string]struct{}),
+		methods:     make(map[string]struct{}),
+		credentials: cfg.AllowCredentials,
+	}
+	for _, o := range cfg.AllowOrigins {
+		o = strings.TrimSpace(o)
+		switch {
+		case o == "*":
+			p.anyOrigin = true
+		case strings.HasPrefix(o, CorsRegexPrefix):
+			reg, err := regexp.Compile(strings.TrimPrefix(o, CorsRegexPrefix))
+			if err != nil {
+				return err
+			}
+			p.patterns = append(p.patterns, reg)
+		case strings.Contains(o, "*"):
+			expr := strings.ReplaceAll(regexp.QuoteMeta(strings.ToLower(o)), `\*`, `[a-z0-9-]+(\.[a-z0-9-]+)*`)
+			p.patterns = append(p.patterns, regexp.MustCompile("^"+expr+"$"))
+		case len(o) > 0:
+			p.exact[strings.ToLower(o)] = struct{}{}
+		}
+	}
+	methods := cfg.AllowMethods
+	if len(methods) == 0 {
+		methods = DefaultCorsMethods
+	}
+	methods = slices.Clone(methods)
+	for i, m := range methods {
+		methods[i] = strings.ToUpper(strings.TrimSpace(m))
+		p.methods[methods[i]] = struct{}{}
+	}
+	p.allowMethods = strings.Join(methods, ",")
+	for _, h := range cfg.AllowHeaders {
+		if h == "*" {
+			p.anyHeader = true
+		}
+	}
+	if !p.anyHeader {
+		p.allowHeaders = strings.Join(cfg.AllowHeaders, ",")
+	}
+	p.exposeHeaders = strings.Join(cfg.ExposeHeaders, ",")
+	if cfg.MaxAge > 0 {
+		p.maxAge = strconv.FormatInt(int64(cfg.MaxAge/time.Second), 10)
+	}
+	c.policy.Store(p)
+	return nil
+}
+
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	o := strings.ToLower(origin)
+	if _, ok := p.exact[o]; ok {
+		return true
+	}
+	for _, reg := range p.patterns {
+		if reg.MatchString(o) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler answers preflight requests and decorates actual ones.
+// Disallowed preflights get 403, disallowed actual requests pass through without CORS headers.
+func (c *CorsHandler) Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		p := c.policy.Load()
+		origin := ctx.GetHeader("Origin")
+		if len(origin) == 0 {
+			ctx.Next()
+			return
+		}
+		h := ctx.Writer.Header()
+		h.Add("Vary", "Origin")
+		preflight := ctx.Request.Method == http.MethodOptions && len(ctx.GetHeader("Access-Control-Request-Method")) > 0
+		if !p.allowOrigin(origin) {
+			if preflight {
+				ctx.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			ctx.Next()
+			return
+		}
+		if p.anyOrigin && !p.credentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if p.credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			if len(p.exposeHeaders) > 0 {
+				h.Set("Access-Control-Expose-Headers", p.exposeHeaders)
+			}
+			ctx.Next()
+			return
+		}
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		if _, ok := p.methods[strings.ToUpper(ctx.GetHeader("Access-Control-Request-Method"))]; !ok {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		h.Set("Access-Control-Allow-Methods", p.allowMethods)
+		if p.anyHeader {
+			if rh := ctx.GetHeader("Access-Control-Request-Headers"); len(rh) > 0 {
+				h.Set("Access-Control-Allow-Headers", rh)
+			}
+		} else if len(p.allowHeaders) > 0 {
+			h.Set("Access-Control-Allow-Headers", p.allowHeaders)
+		}
+		if len(p.maxAge) > 0 {
+			h.Set("Access-Control-Max-Age", p.maxAge)
+		}
+		ctx.AbortWithStatus(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/response"
+	"github.com/skirrund/gcloud/server"
+)
+
+type ErrorHandlerOptions struct {
+	// HideInternal drops the SubMessage of internal errors, e.g. in production profiles
+	HideInternal bool
+}
+
+// AbortWithError hands err to ErrorHandler and stops the handler chain
+func AbortWithError(ctx *gin.Context, err error) {
+	ctx.Error(err)
+	ctx.Abort()
+}
+
+// ErrorHandler recovers panics and answers the last error recorded with ctx.Error
+// using the mapping registered in the server error registry
+func ErrorHandler(opts ErrorHandlerOptions) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.ErrorContext(ctx, "[GIN] recover:", r, "\n", string(debug.Stack()))
+				err, ok := r.(error)
+				if !ok {
+					err = errors.New(fmt.Sprint(r))
+				}
+				writeError(ctx, err, opts)
+			}
+		}()
+		ctx.Next()
+		if len(ctx.Errors) > 0 {
+			writeError(ctx, ctx.Errors.Last().Err, opts)
+		}
+	}
+}
+
+func writeError(ctx *gin.Context, err error, opts ErrorHandlerOptions) {
+	m := server.MapError(err)
+	if m.Internal {
+		logger.ErrorContext(ctx, "[GIN] error:", ctx.Request.URL.Path, ",", fmt.Sprintf("%+v", err))
+	}
+	if ctx.Writer.Written() {
+		return
+	}
+	subMsg := m.SubMsg
+	if m.Internal && opts.HideInternal {
+		subMsg = ""
+	}
+	ctx.AbortWithStatusJSON(m.Status, response.CreateMsgInfo[any](m.Msginfo, subMsg))
+}
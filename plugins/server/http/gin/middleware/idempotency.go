@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/response"
+	"github.com/skirrund/gcloud/tracer"
+	"github.com/skirrund/gcloud/utils"
+)
+
+const (
+	DefaultIdempotencyHeader  = "Idempotency-Key"
+	DefaultIdempotencyPrefix  = "gcloud:idempotency:"
+	DefaultIdempotencyLockTTL = time.Minute
+	DefaultIdempotencyTTL     = 24 * time.Hour
+	// DefaultIdempotencyMaxBody bounds the stored response, larger responses are not replayed
+	DefaultIdempotencyMaxBody = 1 << 20
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	idempotencyInFlight       = "-"
+)
+
+// IdempotencyStore keeps the keys and responses, e.g. in Redis
+type IdempotencyStore interface {
+	// SetNX reports false when the key exists and an error when the store can't be reached
+	SetNX(key string, value string, expiration time.Duration) (bool, error)
+	Get(key string) string
+	Set(key string, value string, expiration time.Duration)
+	Del(keys ...string) int64
+}
+
+type IdempotencyConfig struct {
+	Store IdempotencyStore
+	// Header defaults to DefaultIdempotencyHeader
+	Header string
+	Prefix string
+	// LockTTL bounds how long a request may stay in flight, TTL how long its response is replayed
+	LockTTL time.Duration
+	TTL     time.Duration
+	// Methods defaults to POST
+	Methods []string
+	// Paths are route patterns or URL path prefixes ending with "*", empty covers every route
+	Paths []string
+	// Required rejects covered requests without the header
+	Required bool
+	MaxBody  int
+	// Scope separates the keys of different callers, the UserIDKey set by an auth middleware by default
+	Scope func(ctx *gin.Context) string
+}
+
+type idempotentResponse struct {
+	Status int                 `json:"status"`
+	Header map[string][]string `json:"header"`
+	Body   []byte              `json:"body"`
+}
+
+type recordWriter struct {
+	gin.ResponseWriter
+	body *limitBuffer
+}
+
+func (w *recordWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordWriter) WriteString(s string) (int, error) {
+	w.body.Write([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (cfg *IdempotencyConfig) covers(ctx *gin.Context) bool {
+	if !slices.Contains(cfg.Methods, ctx.Request.Method) {
+		return false
+	}
+	if len(cfg.Paths) == 0 {
+		return true
+	}
+	for _, p := range cfg.Paths {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(ctx.Request.URL.Path, prefix) {
+				return true
+			}
+		} else if p == ctx.FullPath() {
+			return true
+		}
+	}
+	return false
+}
+
+// Idempotency replays the first completed response of an Idempotency-Key to its retries.
+// Keys are scoped by method, route and Scope, so the auth middleware must run before it;
+// 5xx responses and handler errors release the key so the client can retry.
+// When the store can't be reached the request runs without idempotency.
+func Idempotency(cfg IdempotencyConfig) gin.HandlerFunc {
+	if len(cfg.Header) == 0 {
+		cfg.Header = DefaultIdempotencyHeader
+	}
+	if len(cfg.Prefix) == 0 {
+		cfg.Prefix = DefaultIdempotencyPrefix
+	}
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = DefaultIdempotencyLockTTL
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultIdempotencyTTL
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{http.MethodPost}
+	}
+	if cfg.MaxBody <= 0 {
+		cfg.MaxBody = DefaultIdempotencyMaxBody
+	}
+	if cfg.Scope == nil {
+		cfg.Scope = func(ctx *gin.Context) string {
+			return ctx.GetString(UserIDKey)
+		}
+	}
+	return func(ctx *gin.Context) {
+		if !cfg.covers(ctx) {
+			ctx.Next()
+			return
+		}
+		ik := ctx.GetHeader(cfg.Header)
+		if len(ik) == 0 {
+			if cfg.Required {
+				ctx.AbortWithStatusJSON(http.StatusBadRequest, response.CreateMsgInfo[any](response.VALIDATE_ERROR, cfg.Header+" required"))
+				return
+			}
+			ctx.Next()
+			return
+		}
+		key := cfg.Prefix + cfg.Scope(ctx) + ":" + ctx.Request.Method + ctx.FullPath() + ":" + ik
+		locked, err := cfg.Store.SetNX(key, idempotencyInFlight, cfg.LockTTL)
+		if err != nil {
+			logger.WarnContext(ctx, "[GIN] idempotency store error, running without it:", err.Error())
+			ctx.Next()
+			return
+		}
+		if !locked {
+			replay(ctx, cfg.Store.Get(key))
+			return
+		}
+		done := false
+		defer func() {
+			// a panic or an unstored response must not keep the key locked
+			if !done {
+				cfg.Store.Del(key)
+			}
+		}()
+		rw := &recordWriter{ResponseWriter: ctx.Writer, body: &limitBuffer{buf: getBuffer(), max: cfg.MaxBody}}
+		defer rw.body.release()
+		ctx.Writer = rw
+		ctx.Next()
+		// errors are written by ErrorHandler after this middleware returns, the status is still gin's default
+		if len(ctx.Errors) > 0 || !rw.Written() {
+			return
+		}
+		status := rw.Status()
+		if status >= http.StatusInternalServerError || rw.body.truncated {
+			return
+		}
+		rec := idempotentResponse{Status: status, Header: handlerHeader(rw.Header()), Body: rw.body.buf.Bytes()}
+		v, err := utils.MarshalToString(rec)
+		if err != nil {
+			logger.ErrorContext(ctx, "[GIN] idempotency marshal error:", err.Error())
+			return
+		}
+		cfg.Store.Set(key, v, cfg.TTL)
+		done = true
+	}
+}
+
+// handlerHeader drops the headers the surrounding middlewares set for the current request,
+// e.g. the trace id, CORS and the content coding, so a replay carries only what the handler wrote
+func handlerHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, vs := range h {
+		switch k {
+		case "Content-Length", "Date", "Vary", "Content-Encoding", http.CanonicalHeaderKey(tracer.TraceIDKey):
+			continue
+		}
+		if strings.HasPrefix(k, "Access-Control-") {
+			continue
+		}
+		out[k] = slices.Clone(vs)
+	}
+	return out
+}
+
+func replay(ctx *gin.Context, v string) {
+	if len(v) == 0 || v == idempotencyInFlight {
+		ctx.AbortWithStatusJSON(http.StatusConflict, response.CreateMsgInfo[any](response.IDEMPOTENCY_IN_PROGRESS, ""))
+		return
+	}
+	var rec idempotentResponse
+	if err := utils.UnmarshalFromString(v, &rec); err != nil {
+		logger.ErrorContext(ctx, "[GIN] idempotency unmarshal error:", err.Error())
+		ctx.AbortWithStatusJSON(http.StatusConflict, response.CreateMsgInfo[any](response.IDEMPOTENCY_IN_PROGRESS, ""))
+		return
+	}
+	h := ctx.Writer.Header()
+	for k, vs := range rec.Header {
+		// the current request keeps what the middlewares already set
+		if _, ok := h[k]; !ok {
+			h[k] = vs
+		}
+	}
+	h.Set(IdempotentReplayedHeader, "true")
+	ctx.Status(rec.Status)
+	ctx.Writer.Write(rec.Body)
+	ctx.Abort()
+}
@@ -0,0 +1,262 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/tracer"
+	"github.com/skirrund/gcloud/utils/redact"
+	"github.com/skirrund/gcloud/utils/worker"
+)
+
+const MAX_PRINT_BODY_LEN = 2048
+
+var reg = regexp.MustCompile(`.*\.(js|css|png|jpg|jpeg|gif|svg|webp|bmp|html|htm).*$`)
+
+// accessLog writes the request logs, demote them with logger.demote: [http]
+var accessLog = logger.Named("http")
+
+// DefaultSkipContentTypes are never captured; a trailing "/" matches the whole type
+var DefaultSkipContentTypes = []string{
+	"multipart/",
+	"image/",
+	"audio/",
+	"video/",
+	"font/",
+	"application/octet-stream",
+	"application/zip",
+	"application/gzip",
+	"application/pdf",
+	"application/grpc",
+	"text/event-stream",
+}
+
+// RouteLogging overrides the logging behaviour of one route
+type RouteLogging struct {
+	// Disabled skips the log line entirely
+	Disabled bool
+	// SkipRequestBody and SkipResponseBody stop capturing the respective body
+	SkipRequestBody  bool
+	SkipResponseBody bool
+	// MaxBodyLen overrides LoggingConfig.MaxBodyLen when positive
+	MaxBodyLen int
+}
+
+type LoggingConfig struct {
+	// MaxBodyLen is the number of bytes kept from each body, MAX_PRINT_BODY_LEN when zero
+	MaxBodyLen int
+	// SkipContentTypes replaces DefaultSkipContentTypes when not nil
+	SkipContentTypes []string
+	// Routes is keyed by the route pattern (gin.Context.FullPath), e.g. "/user/:id"
+	Routes map[string]RouteLogging
+}
+
+var loggingConfig atomic.Pointer[LoggingConfig]
+
+// SetLoggingConfig replaces the config used by LoggingMiddleware, it is safe to call at runtime
+func SetLoggingConfig(cfg LoggingConfig) {
+	loggingConfig.Store(&cfg)
+}
+
+func currentLoggingConfig() *LoggingConfig {
+	if cfg := loggingConfig.Load(); cfg != nil {
+		return cfg
+	}
+	return &LoggingConfig{}
+}
+
+func (cfg *LoggingConfig) route(path string) RouteLogging {
+	rl := cfg.Routes[path]
+	if rl.MaxBodyLen <= 0 {
+		rl.MaxBodyLen = cfg.MaxBodyLen
+	}
+	if rl.MaxBodyLen <= 0 {
+		rl.MaxBodyLen = MAX_PRINT_BODY_LEN
+	}
+	return rl
+}
+
+func (cfg *LoggingConfig) skipContentType(ct string) bool {
+	if len(ct) == 0 {
+		return false
+	}
+	if mt, _, err := mime.ParseMediaType(ct); err == nil {
+		ct = mt
+	}
+	ct = strings.ToLower(ct)
+	skip := cfg.SkipContentTypes
+	if skip == nil {
+		skip = DefaultSkipContentTypes
+	}
+	for _, s := range skip {
+		if strings.HasSuffix(s, "/") {
+			if strings.HasPrefix(ct, s) {
+				return true
+			}
+		} else if ct == s {
+			return true
+		}
+	}
+	return false
+}
+
+// limitBuffer keeps the first max bytes written to it and silently drops the rest
+type limitBuffer struct {
+	buf       *bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (lb *limitBuffer) Write(p []byte) (int, error) {
+	if lb.buf == nil {
+		return len(p), nil
+	}
+	if remain := lb.max - lb.buf.Len(); remain > 0 {
+		if len(p) > remain {
+			lb.buf.Write(p[:remain])
+			lb.truncated = true
+		} else {
+			lb.buf.Write(p)
+		}
+	} else if len(p) > 0 {
+		lb.truncated = true
+	}
+	return len(p), nil
+}
+
+func (lb *limitBuffer) String() string {
+	if lb.buf == nil {
+		return ""
+	}
+	bb := lb.buf.Bytes()
+	if lb.truncated {
+		// drop a rune cut in half by the limit
+		for i := 1; i < utf8.UTFMax && len(bb) > 0 && !utf8.Valid(bb); i++ {
+			bb = bb[:len(bb)-1]
+		}
+	}
+	return strings.Trim(string(bb), "\n")
+}
+
+func (lb *limitBuffer) release() {
+	if lb.buf != nil {
+		putBuffer(lb.buf)
+		lb.buf = nil
+	}
+}
+
+// teeBody copies the first bytes read by the handler into capture, the body is never read ahead
+type teeBody struct {
+	io.ReadCloser
+	capture *limitBuffer
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		t.capture.Write(p[:n])
+	}
+	return n, err
+}
+
+type bodyLogWriter struct {
+	gin.ResponseWriter
+	capture *limitBuffer
+	skip    func(string) bool
+	checked bool
+}
+
+func (w *bodyLogWriter) check() {
+	if !w.checked {
+		w.checked = true
+		if w.skip(w.Header().Get("Content-Type")) {
+			w.capture.release()
+		}
+	}
+}
+
+func (w *bodyLogWriter) Write(b []byte) (int, error) {
+	w.check()
+	w.capture.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyLogWriter) WriteString(s string) (int, error) {
+	w.check()
+	w.capture.Write([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func requestEnd(path, uri, contentType, method string, start time.Time, strBody, reqBody, status, respCt, traceId string) {
+	if strings.HasPrefix(path, "/metrics") {
+		strBody = "ignore..."
+	}
+	if strings.HasPrefix(path, "/swagger") {
+		return
+	}
+	if reg.MatchString(path) {
+		return
+	}
+	accessLog.Info(fmt.Sprint("\n [GIN] uri:", uri, ", at:", start.Format(time.DateTime),
+		"\n [GIN] trace-id:", traceId,
+		"\n [GIN] content-type:", contentType,
+		"\n [GIN] method:", method,
+		"\n [GIN] body:"+reqBody,
+		"\n [GIN] status:"+status,
+		"\n [GIN] response-content-type:"+respCt,
+		"\n [GIN] response:"+strBody,
+		"\n [GIN] cost:"+strconv.FormatInt(time.Since(start).Milliseconds(), 10)+"ms"))
+}
+
+// LoggingMiddleware logs every request with the config set by SetLoggingConfig.
+// Only the first MaxBodyLen bytes of each body are kept, and only as the handler streams them.
+func LoggingMiddleware(ctx *gin.Context) {
+	cfg := currentLoggingConfig()
+	rl := cfg.route(ctx.FullPath())
+	if rl.Disabled {
+		ctx.Next()
+		return
+	}
+	start := time.Now()
+	req := ctx.Request
+	ct := req.Header.Get("Content-Type")
+	reqCapture := &limitBuffer{max: rl.MaxBodyLen}
+	if !rl.SkipRequestBody && req.Body != nil && !cfg.skipContentType(ct) {
+		reqCapture.buf = getBuffer()
+		req.Body = &teeBody{ReadCloser: req.Body, capture: reqCapture}
+	}
+	respCapture := &limitBuffer{max: rl.MaxBodyLen}
+	if !rl.SkipResponseBody {
+		respCapture.buf = getBuffer()
+	}
+	blw := &bodyLogWriter{ResponseWriter: ctx.Writer, capture: respCapture, skip: cfg.skipContentType}
+	ctx.Writer = blw
+	ctx.Next()
+	strBody := respCapture.String()
+	reqBody := reqCapture.String()
+	respCapture.release()
+	reqCapture.release()
+	rUri, _ := url.QueryUnescape(req.RequestURI)
+	uri1 := req.Host + rUri
+	path := req.URL.Path
+	method := req.Method
+	status := ctx.Writer.Status()
+	respCt := ctx.Writer.Header().Get("Content-Type")
+	traceId := ctx.GetString(tracer.TraceIDKey)
+	worker.AsyncExecute(func() {
+		reqBody := redact.Body(ct, reqBody)
+		strBody := redact.Body(respCt, strBody)
+		requestEnd(path, uri1, ct, method, start, strBody, reqBody, strconv.FormatInt(int64(status), 10), respCt, traceId)
+	})
+}
@@ -2,26 +2,18 @@ package middleware
 
 import (
 	"bytes"
-	"io"
 	"net/http"
-	"net/url"
-	"regexp"
-	"strconv"
-	"strings"
 	"sync"
-	"time"
-	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
-	"github.com/skirrund/gcloud/logger"
 	"github.com/skirrund/gcloud/tracer"
-	"github.com/skirrund/gcloud/utils/worker"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-const MAX_PRINT_BODY_LEN = 2048
-
-var reg = regexp.MustCompile(`.*\.(js|css|png|jpg|jpeg|gif|svg|webp|bmp|html|htm).*$`)
-
 var poll1 = &sync.Pool{
 	New: func() any {
 		return new(bytes.Buffer)
@@ -36,6 +28,7 @@ func putBuffer(buff *bytes.Buffer) {
 	poll1.Put(buff)
 }
 
+// Deprecated: Cors reflects any origin with credentials, use NewCors with an origin allowlist.
 func Cors(c *gin.Context) {
 	method := c.Request.Method
 	origin := c.Request.Header.Get("Origin")
@@ -51,77 +44,50 @@ func Cors(c *gin.Context) {
 	}
 }
 
-type bodyLogWriter struct {
-	gin.ResponseWriter
-	bodyBuf *bytes.Buffer
-}
-
-func (w bodyLogWriter) Write(b []byte) (int, error) {
-	//memory copy here!
-	w.bodyBuf.Write(b)
-	return w.ResponseWriter.Write(b)
-}
-
-func requestEnd(uri, contentType, method string, start time.Time, strBody, reqBody, status, respCt, traceId string) {
-	if strings.HasPrefix(uri, "/metrics") {
-		strBody = "ignore..."
-	}
-	if strings.HasPrefix(uri, "/swagger") {
-		return
-	}
-	if reg.MatchString(uri) {
-		return
-	}
-	logger.Info("\n [GIN] uri:", uri,  ", at:", start.Format(time.DateTime),
-		"\n [GIN] trace-id:", traceId,
-		"\n [GIN] content-type:", contentType,
-		"\n [GIN] method:", method,
-		"\n [GIN] body:"+reqBody,
-		"\n [GIN] status:"+status,
-		"\n [GIN] response-content-type:"+respCt,
-		"\n [GIN] response:"+strBody,
-		"\n [GIN] cost:"+strconv.FormatInt(time.Since(start).Milliseconds(), 10)+"ms")
-}
-
+// TraceMiddleware continues the trace of the W3C traceparent header and starts the server span.
+// The legacy trace id follows the OTel trace id: it is taken from a valid parent, and otherwise
+// read from the header or generated and used as the trace id of the new root span.
 func TraceMiddleware(ctx *gin.Context) {
+	req := ctx.Request
+	c := tracer.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
 	traceId := ctx.GetHeader(tracer.TraceIDKey)
+	if sc := trace.SpanContextFromContext(c); sc.IsValid() {
+		traceId = sc.TraceID().String()
+	}
 	if len(traceId) == 0 {
 		traceId = tracer.GenerateId()
-		ctx.Request.Header.Set(tracer.TraceIDKey, traceId)
 	}
+	if ctx.GetHeader(tracer.TraceIDKey) != traceId {
+		req.Header.Set(tracer.TraceIDKey, traceId)
+	}
+	c = tracer.WithContext(c, traceId)
+
+	route := ctx.FullPath()
+	name := req.Method
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.URLPath(req.URL.Path),
+		semconv.ClientAddress(ctx.ClientIP()),
+		semconv.UserAgentOriginal(req.UserAgent()),
+	}
+	if len(route) > 0 {
+		name += " " + route
+		attrs = append(attrs, semconv.HTTPRoute(route))
+	}
+	c, span := tracer.Start(c, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+	defer span.End()
+	ctx.Request = req.WithContext(c)
+
 	ctx.Set(tracer.TraceIDKey, traceId)
 	ctx.Header(tracer.TraceIDKey, traceId)
 	ctx.Next()
-}
 
-func LoggingMiddleware(ctx *gin.Context) {
-	start := time.Now()
-	blw := bodyLogWriter{bodyBuf: bytes.NewBufferString(""), ResponseWriter: ctx.Writer}
-	ctx.Writer = blw
-	bb, err := io.ReadAll(ctx.Request.Body)
-	if err == nil {
-		ctx.Request.Body = io.NopCloser(bytes.NewBuffer(bb))
+	status := ctx.Writer.Status()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if err := ctx.Errors.Last(); err != nil {
+		span.RecordError(err.Err)
 	}
-	ctx.Next()
-	strBody := strings.Trim(blw.bodyBuf.String(), "\n")
-	if utf8.RuneCountInString(strBody) > MAX_PRINT_BODY_LEN {
-		strBody = strBody[:(MAX_PRINT_BODY_LEN - 1)]
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
 	}
-	buff := getBuffer()
-	defer putBuffer(buff)
-	buff.Write(bb)
-	logbb := buff.Next(MAX_PRINT_BODY_LEN)
-	// if len(bb) > MAX_PRINT_BODY_LEN {
-	// 	bb = bb[:(MAX_PRINT_BODY_LEN - 1)]
-	// }
-	req := ctx.Request
-	rUri, _ := url.QueryUnescape(req.RequestURI)
-	uri1 := req.Host + rUri
-	ct := req.Header.Get("Content-Type")
-	method := req.Method
-	status := ctx.Writer.Status()
-	respCt := ctx.Writer.Header().Get("Content-Type")
-	worker.AsyncExecute(func() {
-		requestEnd(uri1, ct, method, start, strBody, string(logbb), strconv.FormatInt(int64(status), 10), respCt, ctx.GetString(tracer.TraceIDKey))
-	})
 }
@@ -0,0 +1,490 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/skirrund/gcloud/response"
+	"github.com/skirrund/gcloud/server"
+	"github.com/skirrund/gcloud/server/concurrency"
+	"github.com/skirrund/gcloud/server/ratelimit"
+	"github.com/skirrund/gcloud/tracer"
+	"github.com/skirrund/gcloud/utils"
+	"github.com/skirrund/gcloud/utils/gerrors"
+)
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(BodyLimit(8))
+	e.POST("/", func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	for _, tc := range []struct {
+		body    string
+		chunked bool
+		status  int
+	}{
+		{"small", false, http.StatusOK},
+		{"too large body", false, http.StatusRequestEntityTooLarge},
+		{"too large body", true, http.StatusRequestEntityTooLarge},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+		if tc.chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Errorf("body %q chunked %v: status %d, want %d", tc.body, tc.chunked, w.Code, tc.status)
+		}
+	}
+}
+
+func TestLimitBuffer(t *testing.T) {
+	lb := &limitBuffer{buf: getBuffer(), max: 4}
+	defer lb.release()
+	lb.Write([]byte("ab"))
+	lb.Write([]byte("中文"))
+	if s := lb.String(); s != "ab" {
+		t.Errorf("got %q, want %q", s, "ab")
+	}
+	cfg := &LoggingConfig{}
+	if !cfg.skipContentType("multipart/form-data; boundary=x") || cfg.skipContentType("application/json") {
+		t.Error("unexpected skipContentType result")
+	}
+}
+
+func TestCors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, err := NewCors(CorsConfig{
+		AllowOrigins:     []string{"https://a.com", "https://*.b.com", CorsRegexPrefix + `^http://localhost:\d+$`},
+		AllowHeaders:     []string{"*"},
+		AllowCredentials: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := gin.New()
+	e.Use(c.Handler())
+	e.GET("/", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	for _, tc := range []struct {
+		origin string
+		allow  bool
+	}{
+		{"https://a.com", true},
+		{"https://x.y.b.com", true},
+		{"https://b.com", false},
+		{"http://localhost:8080", true},
+		{"https://evil.com", false},
+	} {
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", tc.origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if got := w.Code == http.StatusNoContent; got != tc.allow {
+			t.Errorf("preflight %s: status %d", tc.origin, w.Code)
+		}
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", tc.origin)
+		w = httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if got := w.Header().Get("Access-Control-Allow-Origin") == tc.origin; got != tc.allow {
+			t.Errorf("request %s: allow origin %q", tc.origin, w.Header().Get("Access-Control-Allow-Origin"))
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rl := NewRateLimiter(ratelimit.NewLocal(), []RateLimitRule{
+		{Name: "login", Path: "/login", KeyBy: KeyByHeader + "X-App", Events: 1, Period: time.Minute},
+	})
+	e := gin.New()
+	e.Use(rl.Handler())
+	e.GET("/login", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	e.GET("/other", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	do := func(path, app string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-App", app)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+	if w := do("/login", "a"); w.Code != http.StatusOK {
+		t.Fatalf("first request: %d", w.Code)
+	}
+	w := do("/login", "a")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("second request: %d retry-after %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := do("/login", "b"); w.Code != http.StatusOK {
+		t.Fatalf("other key: %d", w.Code)
+	}
+	if w := do("/other", "a"); w.Code != http.StatusOK {
+		t.Fatalf("unmatched route: %d", w.Code)
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(ErrorHandler(ErrorHandlerOptions{HideInternal: true}))
+	e.GET("/panic", func(ctx *gin.Context) { panic("boom") })
+	e.GET("/biz", func(ctx *gin.Context) {
+		AbortWithError(ctx, gerrors.Wrap(server.NewErrorAllMsg("bad", "detail"), "wrapped"))
+	})
+	e.GET("/timeout", func(ctx *gin.Context) { AbortWithError(ctx, context.DeadlineExceeded) })
+	for _, tc := range []struct {
+		path   string
+		status int
+		code   string
+		sub    string
+	}{
+		{"/panic", http.StatusInternalServerError, response.ERROR, ""},
+		{"/biz", http.StatusOK, response.ERROR, "detail"},
+		{"/timeout", http.StatusGatewayTimeout, response.TIMEOUT_EXCEPTION.Code, ""},
+	} {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		var resp response.Response[any]
+		utils.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != tc.status || resp.Code != tc.code || resp.SubMessage != tc.sub {
+			t.Errorf("%s: status %d body %s", tc.path, w.Code, w.Body.String())
+		}
+	}
+}
+
+type memStore struct {
+	sync.Mutex
+	m map[string]string
+}
+
+func (s *memStore) SetNX(key string, value string, expiration time.Duration) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.m == nil {
+		return false, errors.New("store unavailable")
+	}
+	if _, ok := s.m[key]; ok {
+		return false, nil
+	}
+	s.m[key] = value
+	return true, nil
+}
+
+func (s *memStore) Get(key string) string {
+	s.Lock()
+	defer s.Unlock()
+	return s.m[key]
+}
+
+func (s *memStore) Set(key string, value string, expiration time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.m[key] = value
+}
+
+func (s *memStore) Del(keys ...string) int64 {
+	s.Lock()
+	defer s.Unlock()
+	for _, k := range keys {
+		delete(s.m, k)
+	}
+	return int64(len(keys))
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &memStore{m: make(map[string]string)}
+	calls := 0
+	release := make(chan struct{})
+	e := gin.New()
+	e.Use(Idempotency(IdempotencyConfig{Store: store}))
+	e.POST("/order", func(ctx *gin.Context) {
+		calls++
+		ctx.Header("X-Order", "1")
+		ctx.String(http.StatusCreated, "order-%d", calls)
+	})
+	e.POST("/slow", func(ctx *gin.Context) {
+		<-release
+		ctx.Status(http.StatusOK)
+	})
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set(DefaultIdempotencyHeader, "k1")
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+	first, second := post("/order"), post("/order")
+	if calls != 1 || second.Code != http.StatusCreated || second.Body.String() != "order-1" ||
+		second.Header().Get("X-Order") != "1" || second.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Fatalf("replay: calls %d first %d second %d %q", calls, first.Code, second.Code, second.Body.String())
+	}
+	done := make(chan struct{})
+	go func() {
+		post("/slow")
+		close(done)
+	}()
+	for store.Get(DefaultIdempotencyPrefix+":POST/slow:k1") == "" {
+		time.Sleep(time.Millisecond)
+	}
+	if w := post("/slow"); w.Code != http.StatusConflict {
+		t.Fatalf("in flight: %d", w.Code)
+	}
+	close(release)
+	<-done
+
+	// handler errors are answered by ErrorHandler above, they must not be replayed
+	e = gin.New()
+	e.Use(ErrorHandler(ErrorHandlerOptions{}), Idempotency(IdempotencyConfig{Store: store}))
+	e.POST("/fail", func(ctx *gin.Context) {
+		AbortWithError(ctx, errors.New("boom"))
+	})
+	if first, second := post("/fail"), post("/fail"); first.Code != http.StatusInternalServerError ||
+		second.Code != http.StatusInternalServerError || second.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("error replayed: first %d second %d", first.Code, second.Code)
+	}
+
+	// an unreachable store lets the request through
+	calls = 0
+	e = gin.New()
+	e.Use(Idempotency(IdempotencyConfig{Store: &memStore{}}))
+	e.POST("/order", func(ctx *gin.Context) {
+		calls++
+		ctx.Status(http.StatusCreated)
+	})
+	if w := post("/order"); w.Code != http.StatusCreated || calls != 1 {
+		t.Fatalf("store error: %d", w.Code)
+	}
+}
+
+func TestIdempotencyReplayHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, err := NewCors(CorsConfig{AllowOrigins: []string{"https://a.com", "https://b.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := gin.New()
+	e.Use(TraceMiddleware, c.Handler(), Idempotency(IdempotencyConfig{Store: &memStore{m: make(map[string]string)}}))
+	e.POST("/order", func(ctx *gin.Context) {
+		ctx.Header("X-Order", "1")
+		ctx.String(http.StatusCreated, "order")
+	})
+	post := func(traceId, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/order", nil)
+		req.Header.Set(DefaultIdempotencyHeader, "k1")
+		req.Header.Set(tracer.TraceIDKey, traceId)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+	post("t1", "https://a.com")
+	w := post("t2", "https://b.com")
+	h := w.Header()
+	if h.Get(IdempotentReplayedHeader) != "true" || h.Get("X-Order") != "1" {
+		t.Fatalf("not replayed: %v", h)
+	}
+	if h.Get(tracer.TraceIDKey) != "t2" || h.Get("Access-Control-Allow-Origin") != "https://b.com" || len(h.Values("Vary")) != 1 {
+		t.Fatalf("replayed the headers of the first request: %v", h)
+	}
+}
+
+func TestCompressNegotiate(t *testing.T) {
+	c, err := NewCompressor(CompressConfig{Encodings: []string{EncodingZstd, EncodingBrotli, EncodingGzip}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := c.policy.Load()
+	for ae, want := range map[string]string{
+		"":                              "",
+		"identity":                      "",
+		"gzip, deflate, br, zstd":       EncodingZstd,
+		"gzip;q=1, br;q=0.8":            EncodingGzip,
+		"zstd;q=0, *":                   EncodingBrotli,
+		"x-gzip":                        EncodingGzip,
+		"deflate":                       "",
+		"*;q=0.5, gzip;q=0.6, br;q=0.1": EncodingGzip,
+	} {
+		if got := p.negotiate(ae); got != want {
+			t.Errorf("negotiate(%q) = %q, want %q", ae, got, want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, err := NewCompressor(CompressConfig{MinSize: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	large := strings.Repeat(`{"name":"gcloud"}`, 100)
+	var logged string
+	e := gin.New()
+	e.Use(c.Handler(), func(ctx *gin.Context) {
+		capture := &limitBuffer{buf: getBuffer(), max: 1 << 20}
+		ctx.Writer = &bodyLogWriter{ResponseWriter: ctx.Writer, capture: capture, skip: func(string) bool { return false }}
+		ctx.Next()
+		logged = capture.String()
+		capture.release()
+	})
+	e.GET("/large", func(ctx *gin.Context) { ctx.Data(http.StatusOK, "application/json", []byte(large)) })
+	e.GET("/small", func(ctx *gin.Context) { ctx.String(http.StatusOK, "ok") })
+	e.GET("/png", func(ctx *gin.Context) { ctx.Data(http.StatusOK, "image/png", []byte(large)) })
+	e.GET("/sse", func(ctx *gin.Context) {
+		for i := 0; i < 3; i++ {
+			ctx.SSEvent("msg", large)
+			ctx.Writer.Flush()
+		}
+	})
+	for _, enc := range []string{EncodingGzip, EncodingZstd} {
+		req := httptest.NewRequest(http.MethodGet, "/large", nil)
+		req.Header.Set("Accept-Encoding", enc)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if w.Header().Get("Content-Encoding") != enc || w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Fatalf("%s: headers %v", enc, w.Header())
+		}
+		var r io.Reader
+		if enc == EncodingGzip {
+			r, err = gzip.NewReader(w.Body)
+		} else {
+			r, err = zstd.NewReader(w.Body)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil || string(b) != large {
+			t.Errorf("%s: decoded %d bytes, err %v", enc, len(b), err)
+		}
+		if logged != large {
+			t.Errorf("%s: logged body is not the uncompressed one", enc)
+		}
+	}
+	for _, path := range []string{"/small", "/png", "/sse"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if ce := w.Header().Get("Content-Encoding"); len(ce) > 0 || w.Body.Len() == 0 {
+			t.Errorf("%s: content-encoding %q, body %d bytes", path, ce, w.Body.Len())
+		}
+	}
+}
+
+func TestCompressWritten(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, err := NewCompressor(CompressConfig{MinSize: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	large := strings.Repeat(`{"name":"gcloud"}`, 100)
+	calls := 0
+	e := gin.New()
+	e.Use(c.Handler(), ErrorHandler(ErrorHandlerOptions{}), Idempotency(IdempotencyConfig{Store: &memStore{m: make(map[string]string)}}))
+	e.POST("/order", func(ctx *gin.Context) {
+		calls++
+		ctx.Data(http.StatusOK, "application/json", []byte(large))
+	})
+	e.POST("/partial", func(ctx *gin.Context) {
+		ctx.Data(http.StatusOK, "application/json", []byte(large))
+		ctx.Error(errors.New("late"))
+	})
+	post := func(path string) (*httptest.ResponseRecorder, string) {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set(DefaultIdempotencyHeader, "k1")
+		req.Header.Set("Accept-Encoding", EncodingZstd)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		r, err := zstd.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(r)
+		return w, string(b)
+	}
+	post("/order")
+	w, body := post("/order")
+	if calls != 1 || w.Header().Get(IdempotentReplayedHeader) != "true" || body != large {
+		t.Fatalf("compressed response not replayed: calls %d, %d bytes", calls, len(body))
+	}
+	// ErrorHandler must not append its envelope to a started compressed stream
+	if _, body := post("/partial"); body != large {
+		t.Fatalf("partial body %d bytes", len(body))
+	}
+}
+
+func TestOpenTracing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mt := mocktracer.New()
+	e := gin.New()
+	e.Use(TraceMiddleware, OpenTracing(mt))
+	var inHandler opentracing.Span
+	e.GET("/user/:id", func(c *gin.Context) {
+		inHandler = opentracing.SpanFromContext(c.Request.Context())
+		c.Status(http.StatusBadGateway)
+	})
+
+	parent := mt.StartSpan("client")
+	req := httptest.NewRequest(http.MethodGet, "/user/1", nil)
+	if err := mt.Inject(parent.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header)); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+
+	spans := mt.FinishedSpans()
+	if len(spans) != 1 || inHandler != spans[0] {
+		t.Fatalf("unexpected spans %v", spans)
+	}
+	s := spans[0]
+	if s.OperationName != "GET /user/:id" || s.ParentID != parent.(*mocktracer.MockSpan).SpanContext.SpanID {
+		t.Fatalf("span %s with parent %d", s.OperationName, s.ParentID)
+	}
+	if s.Tag("http.status_code") != uint16(http.StatusBadGateway) || s.Tag("error") != true || s.Tag("http.route") != "/user/:id" {
+		t.Fatalf("unexpected tags %v", s.Tags())
+	}
+	if s.Tag(tracer.TraceIDKey) != w.Header().Get(tracer.TraceIDKey) {
+		t.Fatalf("legacy trace id tag %v", s.Tag(tracer.TraceIDKey))
+	}
+}
+
+func TestConcurrencyLimitPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	l := concurrency.New(concurrency.Fixed(2))
+	e := gin.New()
+	e.Use(ErrorHandler(ErrorHandlerOptions{}), ConcurrencyLimit(l, PathPriority(nil)))
+	e.GET("/panic", func(ctx *gin.Context) {
+		panic("boom")
+	})
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("request %d: %d", i, w.Code)
+		}
+	}
+	if n := l.Inflight(); n != 0 {
+		t.Fatalf("in flight %d after panics", n)
+	}
+}
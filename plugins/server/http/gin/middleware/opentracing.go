@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/skirrund/gcloud/tracer"
+)
+
+// OpenTracing starts a server span continuing the one of the request headers, B3 for the zipkin tracer,
+// tags it with the route and the status and puts it on the request context.
+// It must run after TraceMiddleware so the span carries the legacy trace id.
+func OpenTracing(t opentracing.Tracer) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		req := ctx.Request
+		// a missing or malformed parent starts a new trace
+		parent, _ := t.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
+		route := ctx.FullPath()
+		name := req.Method
+		if len(route) > 0 {
+			name += " " + route
+		}
+		span := t.StartSpan(name, ext.RPCServerOption(parent))
+		defer span.Finish()
+		ext.HTTPMethod.Set(span, req.Method)
+		ext.HTTPUrl.Set(span, req.URL.Path)
+		if len(route) > 0 {
+			span.SetTag("http.route", route)
+		}
+		if id := ctx.GetString(tracer.TraceIDKey); len(id) > 0 {
+			span.SetTag(tracer.TraceIDKey, id)
+		}
+		ctx.Request = req.WithContext(opentracing.ContextWithSpan(req.Context(), span))
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		ext.HTTPStatusCode.Set(span, uint16(status))
+		if err := ctx.Errors.Last(); err != nil {
+			span.LogKV("error", err.Error())
+		}
+		if status >= http.StatusInternalServerError {
+			ext.Error.Set(span, true)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/response"
+	"github.com/skirrund/gcloud/server/ratelimit"
+)
+
+// UserIDKey is the gin context key holding the authenticated user id
+const UserIDKey = "gcloud.userId"
+
+const (
+	KeyByIP     = "ip"
+	KeyByUser   = "user"
+	KeyByRoute  = "route"
+	KeyByHeader = "header:"
+)
+
+type RateLimitRule struct {
+	Name string
+	// Path is a route pattern ("/user/:id"), or a URL path prefix ending with "*"; empty matches all
+	Path string
+	// Methods restricts the rule to some methods, empty matches all
+	Methods []string
+	// KeyBy is KeyByIP (default), KeyByUser, KeyByRoute or KeyByHeader followed by the header name.
+	// KeyByUser falls back to the client ip for anonymous requests.
+	KeyBy  string
+	Events int
+	Period time.Duration
+	Burst  int
+}
+
+func (r *RateLimitRule) match(ctx *gin.Context) bool {
+	if len(r.Methods) > 0 {
+		found := false
+		for _, m := range r.Methods {
+			if strings.EqualFold(m, ctx.Request.Method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.Path) == 0 {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(r.Path, "*"); ok {
+		return strings.HasPrefix(ctx.Request.URL.Path, prefix)
+	}
+	return r.Path == ctx.FullPath()
+}
+
+func (r *RateLimitRule) key(ctx *gin.Context) string {
+	switch {
+	case r.KeyBy == KeyByRoute:
+		return r.Name + ":" + ctx.Request.Method + ctx.FullPath()
+	case r.KeyBy == KeyByUser:
+		if uid := ctx.GetString(UserIDKey); len(uid) > 0 {
+			return r.Name + ":u:" + uid
+		}
+	case strings.HasPrefix(r.KeyBy, KeyByHeader):
+		return r.Name + ":h:" + ctx.GetHeader(strings.TrimPrefix(r.KeyBy, KeyByHeader))
+	}
+	return r.Name + ":ip:" + ctx.ClientIP()
+}
+
+// RateLimiter checks every matching rule against a limiter, rules can be replaced at runtime
+type RateLimiter struct {
+	limiter ratelimit.Limiter
+	rules   atomic.Pointer[[]RateLimitRule]
+}
+
+func NewRateLimiter(limiter ratelimit.Limiter, rules []RateLimitRule) *RateLimiter {
+	rl := &RateLimiter{limiter: limiter}
+	rl.Update(rules)
+	return rl
+}
+
+func (rl *RateLimiter) Update(rules []RateLimitRule) {
+	rl.rules.Store(&rules)
+}
+
+// Handler answers 429 with REQUEST_FREQUENTLY_ERROR and Retry-After once a rule is exhausted.
+// Limiter errors are logged and let the request through.
+func (rl *RateLimiter) Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		rules := *rl.rules.Load()
+		for i := range rules {
+			rule := &rules[i]
+			if !rule.match(ctx) {
+				continue
+			}
+			limit := ratelimit.Limit{Events: rule.Events, Period: rule.Period, Burst: rule.Burst}
+			res, err := rl.limiter.Allow(ctx.Request.Context(), rule.key(ctx), limit)
+			if err != nil {
+				logger.ErrorContext(ctx, "[GIN] ratelimit error:", rule.Name, ",", err.Error())
+				continue
+			}
+			if !res.Allowed {
+				ctx.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(res.RetryAfter.Seconds())), 10))
+				ctx.AbortWithStatusJSON(http.StatusTooManyRequests, response.CreateMsgInfo[any](response.REQUEST_FREQUENTLY_ERROR, rule.Name))
+				return
+			}
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package gin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/plugins/server/http/gin/auth"
+	"github.com/skirrund/gcloud/plugins/server/http/gin/openapi"
+)
+
+const (
+	OPENAPI_ENABLED_KEY     = "server.openapi.enabled"
+	OPENAPI_PATH_KEY        = "server.openapi.path"
+	OPENAPI_TITLE_KEY       = "server.openapi.title"
+	OPENAPI_DESCRIPTION_KEY = "server.openapi.description"
+	OPENAPI_VERSION_KEY     = "server.openapi.version"
+	OPENAPI_SERVERS_KEY     = "server.openapi.servers"
+	OPENAPI_EXCLUDE_KEY     = "server.openapi.exclude"
+	// OPENAPI_DESCRIBED_KEY leaves out the routes without openapi.Describe metadata
+	OPENAPI_DESCRIBED_KEY  = "server.openapi.described"
+	OPENAPI_ASSETS_URL_KEY = "server.openapi.assetsUrl"
+	DefaultOpenAPIPath     = "/openapi"
+	// OpenAPI security scheme names usable in openapi.Route.Security
+	OpenAPISecurityBearer = "bearer"
+	OpenAPISecurityAPIKey = "apiKey"
+)
+
+func registerOpenAPI(s *gin.Engine) {
+	cfg := env.GetInstance()
+	if !cfg.GetBool(OPENAPI_ENABLED_KEY) {
+		return
+	}
+	opts := openapi.UIOptions{
+		Options: openapi.Options{
+			Info: openapi.Info{
+				Title:       cfg.GetStringWithDefault(OPENAPI_TITLE_KEY, cfg.GetString(env.SERVER_SERVERNAME_KEY)),
+				Description: cfg.GetString(OPENAPI_DESCRIPTION_KEY),
+				Version:     cfg.GetString(OPENAPI_VERSION_KEY),
+			},
+			Described: cfg.GetBool(OPENAPI_DESCRIBED_KEY),
+			SecuritySchemes: map[string]*openapi.SecurityScheme{
+				OpenAPISecurityBearer: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				OpenAPISecurityAPIKey: {Type: "apiKey", In: "header", Name: cfg.GetStringWithDefault(auth.APIKEY_HEADER_KEY, auth.DefaultAPIKeyHeader)},
+			},
+		},
+		AssetsURL: cfg.GetString(OPENAPI_ASSETS_URL_KEY),
+	}
+	if exclude := cfg.GetStringSlice(OPENAPI_EXCLUDE_KEY); len(exclude) > 0 {
+		opts.Exclude = append(exclude, openapi.DefaultExclude...)
+	}
+	for _, url := range cfg.GetStringSlice(OPENAPI_SERVERS_KEY) {
+		opts.Servers = append(opts.Servers, openapi.Server{URL: url})
+	}
+	path := cfg.GetStringWithDefault(OPENAPI_PATH_KEY, DefaultOpenAPIPath)
+	logger.Info("[GIN] openapi enabled:", path)
+	openapi.Register(s, path, opts)
+}
@@ -0,0 +1,60 @@
+package openapi
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed ui/index.html
+var indexHTML string
+
+// assets are the swagger-ui-dist files the page loads, see ui/assets/NOTICE
+//
+//go:embed ui/assets
+var assets embed.FS
+
+var indexTemplate = template.Must(template.New("index").Parse(indexHTML))
+
+type UIOptions struct {
+	Options
+	// AssetsURL is the base URL of another swagger-ui-dist, the embedded files are served when empty
+	AssetsURL string
+}
+
+// Register serves the UI at path, its files at path + "/assets" and the document at path + "/openapi.json".
+// The document is generated on the first request, once every route has been registered.
+func Register(engine *gin.Engine, path string, opts UIOptions) {
+	path = "/" + strings.Trim(path, "/")
+	exclude := opts.Exclude
+	if exclude == nil {
+		exclude = DefaultExclude
+	}
+	opts.Exclude = append([]string{path}, exclude...)
+	// relative so the page keeps working behind a path rewriting proxy
+	base := path[strings.LastIndex(path, "/")+1:]
+	assetsURL := strings.TrimSuffix(opts.AssetsURL, "/")
+	if len(assetsURL) == 0 {
+		sub, _ := fs.Sub(assets, "ui/assets")
+		engine.StaticFS(path+"/assets", http.FS(sub))
+		assetsURL = base + "/assets"
+	}
+	specPath := path + "/openapi.json"
+	spec := sync.OnceValue(func() *Document { return Generate(engine, opts.Options) })
+	engine.GET(specPath, func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, spec())
+	})
+	engine.GET(path, func(ctx *gin.Context) {
+		ctx.Header("Content-Type", "text/html; charset=utf-8")
+		indexTemplate.Execute(ctx.Writer, map[string]string{
+			"Title":     opts.Info.Title,
+			"AssetsURL": assetsURL,
+			"SpecURL":   base + "/openapi.json",
+		})
+	})
+}
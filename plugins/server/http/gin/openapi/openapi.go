@@ -0,0 +1,305 @@
+// Package openapi generates an OpenAPI 3 document from the routes of a gin engine and
+// the request/response types described for them, and serves it with an embedded UI.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/response"
+)
+
+// Route describes the handler of one route
+type Route struct {
+	Summary     string
+	Description string
+	Tags        []string
+	OperationID string
+	Deprecated  bool
+	// Request is a value of the type the handler binds, e.g. CreateUserReq{}.
+	// Fields tagged uri (or path), header and form become parameters, the rest the JSON body.
+	Request any
+	// Response is a value of the 200 response type, e.g. response.Response[page.PagingResult[[]User]]{}
+	Response any
+	// Responses documents other status codes
+	Responses map[int]any
+	// Security names the security schemes that apply, e.g. "bearer"
+	Security []string
+}
+
+var (
+	mu     sync.RWMutex
+	routes = make(map[string]Route)
+)
+
+func routeKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// Describe records the metadata of a route, path is the full gin path e.g. "/user/:id"
+func Describe(method, path string, r Route) {
+	mu.Lock()
+	defer mu.Unlock()
+	routes[routeKey(method, path)] = r
+}
+
+func described(method, path string) (Route, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	r, ok := routes[routeKey(method, path)]
+	return r, ok
+}
+
+// Handle registers the handlers on group and describes the route in one go
+func Handle(group *gin.RouterGroup, method, path string, r Route, handlers ...gin.HandlerFunc) gin.IRoutes {
+	full := strings.TrimSuffix(group.BasePath(), "/") + "/" + strings.TrimPrefix(path, "/")
+	if full != "/" {
+		full = strings.TrimSuffix(full, "/")
+	}
+	Describe(method, full, r)
+	return group.Handle(method, path, handlers...)
+}
+
+type Options struct {
+	Info    Info
+	Servers []Server
+	// Exclude drops routes whose path starts with any of the prefixes
+	Exclude []string
+	// Described only documents routes that have been described
+	Described bool
+	// SecuritySchemes are referenced by Route.Security
+	SecuritySchemes map[string]*SecurityScheme
+}
+
+// DefaultExclude are internal routes left out of the document
+var DefaultExclude = []string{"/debug/pprof", "/metrics", "/health"}
+
+// Generate builds the document of every route registered on engine
+func Generate(engine *gin.Engine, opts Options) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    opts.Info,
+		Servers: opts.Servers,
+		Paths:   make(map[string]*PathItem),
+	}
+	if len(doc.Info.Title) == 0 {
+		doc.Info.Title = "API"
+	}
+	if len(doc.Info.Version) == 0 {
+		doc.Info.Version = "1.0.0"
+	}
+	exclude := opts.Exclude
+	if exclude == nil {
+		exclude = DefaultExclude
+	}
+	b := newSchemaBuilder()
+	tags := make(map[string]struct{})
+	for _, ri := range engine.Routes() {
+		if excluded(ri.Path, exclude) {
+			continue
+		}
+		r, ok := described(ri.Method, ri.Path)
+		if !ok && opts.Described {
+			continue
+		}
+		path, params := convertPath(ri.Path)
+		op := b.operation(ri.Method, params, r)
+		if len(op.OperationID) == 0 {
+			op.OperationID = operationID(ri.Method, ri.Path)
+		}
+		item := doc.Paths[path]
+		if item == nil {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		item.set(ri.Method, op)
+		for _, t := range op.Tags {
+			tags[t] = struct{}{}
+		}
+	}
+	for t := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: t})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	doc.Components.Schemas = b.schemas
+	doc.Components.SecuritySchemes = opts.SecuritySchemes
+	return doc
+}
+
+func excluded(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// convertPath turns "/user/:id/*file" into "/user/{id}/{file}" and returns the parameter names
+func convertPath(path string) (string, []string) {
+	segs := strings.Split(path, "/")
+	var params []string
+	for i, s := range segs {
+		if len(s) > 1 && (s[0] == ':' || s[0] == '*') {
+			params = append(params, s[1:])
+			segs[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/"), params
+}
+
+func operationID(method, path string) string {
+	id := strings.ToLower(method) + nonIdentReg.ReplaceAllString(path, "_")
+	return strings.TrimSuffix(id, "_")
+}
+
+func (p *PathItem) set(method string, op *Operation) {
+	switch method {
+	case http.MethodGet:
+		p.Get = op
+	case http.MethodPut:
+		p.Put = op
+	case http.MethodPost:
+		p.Post = op
+	case http.MethodDelete:
+		p.Delete = op
+	case http.MethodOptions:
+		p.Options = op
+	case http.MethodHead:
+		p.Head = op
+	case http.MethodPatch:
+		p.Patch = op
+	}
+}
+
+func (b *schemaBuilder) operation(method string, pathParams []string, r Route) *Operation {
+	op := &Operation{
+		Tags:        r.Tags,
+		Summary:     r.Summary,
+		Description: r.Description,
+		OperationID: r.OperationID,
+		Deprecated:  r.Deprecated,
+		Responses:   make(map[string]*Response),
+	}
+	for _, s := range r.Security {
+		op.Security = append(op.Security, map[string][]string{s: {}})
+	}
+	var reqType reflect.Type
+	if r.Request != nil {
+		reqType = reflect.TypeOf(r.Request)
+	}
+	bound := make(map[string]field)
+	for _, f := range fields(reqType, pathLocation) {
+		bound[f.name] = f
+	}
+	// the route template decides the path parameters, the request type only describes them
+	for _, name := range pathParams {
+		if f, ok := bound[name]; ok {
+			op.Parameters = append(op.Parameters, b.parameter("path", f, true))
+		} else {
+			op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	for _, f := range fields(reqType, headerLocation) {
+		op.Parameters = append(op.Parameters, b.parameter("header", f, f.required))
+	}
+	if reqType != nil {
+		bodyless := method == http.MethodGet || method == http.MethodHead || method == http.MethodDelete
+		formFields := fields(reqType, formLocation)
+		if bodyless {
+			for _, f := range formFields {
+				op.Parameters = append(op.Parameters, b.parameter("query", f, f.required))
+			}
+		} else {
+			op.RequestBody = b.requestBody(reqType, formFields)
+		}
+	}
+	op.Responses["200"] = b.response(http.StatusOK, r.Response)
+	for code, v := range r.Responses {
+		op.Responses[strconv.Itoa(code)] = b.response(code, v)
+	}
+	// errors are answered with the common envelope, see middleware.ErrorHandler
+	op.Responses["default"] = b.response(0, response.Response[any]{})
+	return op
+}
+
+func (b *schemaBuilder) parameter(in string, f field, required bool) *Parameter {
+	s := b.schema(f.typ)
+	applyRules(s, f.rules)
+	if len(f.example) > 0 {
+		s.Example = f.example
+	}
+	return &Parameter{Name: f.name, In: in, Description: f.description, Required: required, Schema: s}
+}
+
+// requestBody documents the JSON fields, or the form fields when the type binds no JSON field
+// explicitly; a file field switches the form to multipart
+func (b *schemaBuilder) requestBody(t reflect.Type, formFields []field) *RequestBody {
+	body := &RequestBody{Required: true, Content: make(map[string]*MediaType)}
+	jsonTagged := false
+	for _, f := range fields(t, jsonLocation) {
+		if _, tagged := lookupJSON(t, f.name); tagged {
+			jsonTagged = true
+			break
+		}
+	}
+	if jsonTagged || len(formFields) == 0 {
+		body.Content[gin.MIMEJSON] = &MediaType{Schema: b.schema(t)}
+		return body
+	}
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	mt := gin.MIMEPOSTForm
+	for _, f := range formFields {
+		fs := b.schema(f.typ)
+		applyField(fs, f)
+		if fs.Format == "binary" || (fs.Items != nil && fs.Items.Format == "binary") {
+			mt = gin.MIMEMultipartPOSTForm
+		}
+		s.Properties[f.name] = fs
+		if f.required {
+			s.Required = append(s.Required, f.name)
+		}
+	}
+	body.Content[mt] = &MediaType{Schema: s}
+	return body
+}
+
+// lookupJSON reports whether the JSON field name comes from a json tag
+func lookupJSON(t reflect.Type, name string) (reflect.StructField, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("json")
+		if ok && strings.Split(tag, ",")[0] == name {
+			return sf, true
+		}
+		if sf.Anonymous {
+			if f, ok := lookupJSON(sf.Type, name); ok {
+				return f, true
+			}
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func (b *schemaBuilder) response(code int, v any) *Response {
+	desc := http.StatusText(code)
+	if code == 0 {
+		desc = "error"
+	}
+	resp := &Response{Description: desc}
+	if v != nil {
+		resp.Content = map[string]*MediaType{gin.MIMEJSON: {Schema: b.schema(reflect.TypeOf(v))}}
+	}
+	return resp
+}
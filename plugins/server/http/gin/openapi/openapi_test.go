@@ -0,0 +1,103 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/page"
+	"github.com/skirrund/gcloud/response"
+)
+
+type user struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name" binding:"required,min=2,max=20" tip:"user name"`
+	Email  string   `json:"email" validate:"omitempty,email"`
+	Status string   `json:"status" binding:"oneof=active blocked"`
+	Tags   []string `json:"tags" binding:"max=5,dive,min=1"`
+	Friend *user    `json:"friend,omitempty"`
+}
+
+type listReq struct {
+	Page  int    `form:"page" binding:"gte=1"`
+	Token string `header:"X-Token" binding:"required"`
+	Org   string `uri:"org"`
+}
+
+func TestGenerate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	g := e.Group("/api")
+	ok := func(ctx *gin.Context) {}
+	Handle(g, http.MethodGet, "/:org/users", Route{Summary: "list", Tags: []string{"user"}, Request: listReq{},
+		Response: response.Response[page.PagingResult[[]user]]{}}, ok)
+	Handle(g, http.MethodPost, "/users", Route{Request: user{}, Response: response.Response[user]{}}, ok)
+	e.GET("/files/*path", ok)
+	e.GET("/metrics", ok)
+	Register(e, "/openapi", UIOptions{})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi", nil))
+	if !strings.Contains(w.Body.String(), `src="openapi/assets/swagger-ui-bundle.js"`) {
+		t.Fatalf("page does not load the embedded assets: %s", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi/assets/swagger-ui.css", nil))
+	if w.Code != http.StatusOK || w.Body.Len() == 0 {
+		t.Fatalf("embedded asset: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi/openapi.json", nil))
+	var doc Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc.Paths["/metrics"]; ok {
+		t.Error("/metrics should be excluded")
+	}
+	if p := doc.Paths["/files/{path}"]; p == nil || p.Get == nil || len(p.Get.Parameters) != 1 {
+		t.Errorf("undescribed route: %+v", p)
+	}
+	list := doc.Paths["/api/{org}/users"].Get
+	in := map[string]string{}
+	for _, p := range list.Parameters {
+		in[p.Name] = p.In
+	}
+	if in["org"] != "path" || in["page"] != "query" || in["X-Token"] != "header" {
+		t.Errorf("parameters: %v", in)
+	}
+	ref := list.Responses["200"].Content[gin.MIMEJSON].Schema.Ref
+	if ref != "#/components/schemas/Response_PagingResult_Arrayuser" {
+		t.Errorf("response ref: %s", ref)
+	}
+	u := doc.Components.Schemas["user"]
+	if u == nil {
+		t.Fatalf("schemas: %v", doc.Components.Schemas)
+	}
+	name := u.Properties["name"]
+	if *name.MinLength != 2 || *name.MaxLength != 20 || name.Description != "user name" || strings.Join(u.Required, ",") != "name" {
+		t.Errorf("name: %+v, required %v", name, u.Required)
+	}
+	if u.Properties["email"].Format != "email" || len(u.Properties["status"].Enum) != 2 {
+		t.Errorf("email/status: %+v %+v", u.Properties["email"], u.Properties["status"])
+	}
+	if tags := u.Properties["tags"]; *tags.MaxItems != 5 || *tags.Items.MinLength != 1 {
+		t.Errorf("tags: %+v", tags)
+	}
+	if u.Properties["friend"].Ref != "#/components/schemas/user" {
+		t.Errorf("friend: %+v", u.Properties["friend"])
+	}
+	if body := doc.Paths["/api/users"].Post.RequestBody; body == nil || body.Content[gin.MIMEJSON] == nil {
+		t.Errorf("request body: %+v", body)
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi", nil))
+	if !strings.Contains(w.Body.String(), "openapi.json") {
+		t.Errorf("ui page: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,327 @@
+package openapi
+
+import (
+	"encoding/json"
+	"mime/multipart"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	fileHeaderType = reflect.TypeOf(multipart.FileHeader{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	pkgPathReg     = regexp.MustCompile(`[\w.\-]+(/[\w.\-]+)*\.`)
+	nonIdentReg    = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// schemaBuilder turns Go types into schemas, named structs are collected into components
+type schemaBuilder struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{schemas: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+// typeName drops package paths from generic arguments, Response[page.PagingResult[[]model.User]] becomes
+// Response_PagingResult_ArrayUser
+func (b *schemaBuilder) typeName(t reflect.Type) string {
+	if n, ok := b.names[t]; ok {
+		return n
+	}
+	base := strings.ReplaceAll(t.Name(), "interface {}", "any")
+	base = pkgPathReg.ReplaceAllString(base, "")
+	base = strings.ReplaceAll(base, "[]", "Array")
+	base = strings.Trim(nonIdentReg.ReplaceAllString(base, "_"), "_")
+	name := base
+	for i := 2; ; i++ {
+		if _, taken := b.schemas[name]; !taken {
+			break
+		}
+		name = base + strconv.Itoa(i)
+	}
+	b.names[t] = name
+	return name
+}
+
+// schema returns the schema of t, named structs are returned as references
+func (b *schemaBuilder) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case fileHeaderType:
+		return &Schema{Type: "string", Format: "binary"}
+	case rawMessageType:
+		return &Schema{}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		if len(t.Name()) == 0 {
+			return b.object(t, jsonLocation)
+		}
+		name, ok := b.names[t]
+		if !ok {
+			name = b.typeName(t)
+			// reserve the name first so recursive types end up as references
+			b.schemas[name] = &Schema{}
+			*b.schemas[name] = *b.object(t, jsonLocation)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// object builds an inline object schema from the fields bound at loc
+func (b *schemaBuilder) object(t reflect.Type, loc location) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, f := range fields(t, loc) {
+		fs := b.schema(f.typ)
+		applyField(fs, f)
+		if len(fs.Ref) > 0 && len(fs.Description) > 0 {
+			// siblings of $ref are ignored in 3.0, wrap the reference instead
+			fs = &Schema{Description: fs.Description, AllOf: []*Schema{{Ref: fs.Ref}}}
+		}
+		s.Properties[f.name] = fs
+		if f.required {
+			s.Required = append(s.Required, f.name)
+		}
+	}
+	return s
+}
+
+type location int
+
+const (
+	jsonLocation location = iota
+	queryLocation
+	formLocation
+	pathLocation
+	headerLocation
+)
+
+// tagOf returns the struct tag gin reads at loc, path parameters accept both "uri" and "path"
+func tagOf(sf reflect.StructField, loc location) (string, bool) {
+	switch loc {
+	case queryLocation, formLocation:
+		return sf.Tag.Lookup("form")
+	case pathLocation:
+		if v, ok := sf.Tag.Lookup("uri"); ok {
+			return v, ok
+		}
+		return sf.Tag.Lookup("path")
+	case headerLocation:
+		return sf.Tag.Lookup("header")
+	}
+	return sf.Tag.Lookup("json")
+}
+
+type field struct {
+	name        string
+	typ         reflect.Type
+	description string
+	example     string
+	required    bool
+	rules       []string
+}
+
+// fields lists the exported fields bound at loc, anonymous structs are flattened like encoding/json does.
+// JSON falls back to the field name, the other locations only bind tagged fields.
+func fields(t reflect.Type, loc location) []field {
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var out []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, tagged := tagOf(sf, loc)
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		ft := sf.Type
+		if sf.Anonymous && len(name) == 0 {
+			et := ft
+			if et.Kind() == reflect.Pointer {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				out = append(out, fields(et, loc)...)
+				continue
+			}
+		}
+		if !sf.IsExported() || (!tagged && loc != jsonLocation) {
+			continue
+		}
+		if len(name) == 0 {
+			name = sf.Name
+		}
+		if loc == jsonLocation && strings.Contains(opts, "string") {
+			ft = reflect.TypeOf("")
+		}
+		f := field{name: name, typ: ft, description: sf.Tag.Get("tip"), example: sf.Tag.Get("example")}
+		if d := sf.Tag.Get("description"); len(d) > 0 {
+			f.description = d
+		}
+		for _, key := range []string{"binding", "validate"} {
+			for _, r := range strings.Split(sf.Tag.Get(key), ",") {
+				if r = strings.TrimSpace(r); len(r) > 0 {
+					f.rules = append(f.rules, r)
+				}
+			}
+		}
+		// a required after dive is about the elements
+		if i := slices.Index(f.rules, "required"); i >= 0 && !slices.Contains(f.rules[:i], "dive") {
+			f.required = true
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+func applyField(s *Schema, f field) {
+	s.Description = f.description
+	if len(f.example) > 0 {
+		s.Example = f.example
+	}
+	applyRules(s, f.rules)
+}
+
+// applyRules maps binding/validate rules to schema keywords, rules after "dive" apply to the items
+func applyRules(s *Schema, rules []string) {
+	for i, r := range rules {
+		if r == "dive" {
+			if s.Items != nil {
+				applyRules(s.Items, rules[i+1:])
+			} else if s.AdditionalProperties != nil {
+				applyRules(s.AdditionalProperties, rules[i+1:])
+			}
+			return
+		}
+		name, param, _ := strings.Cut(r, "=")
+		switch name {
+		case "min", "gte":
+			bound(s, param, true, false)
+		case "max", "lte":
+			bound(s, param, false, false)
+		case "gt":
+			bound(s, param, true, true)
+		case "lt":
+			bound(s, param, false, true)
+		case "len":
+			bound(s, param, true, false)
+			bound(s, param, false, false)
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				s.Enum = append(s.Enum, enumValue(s, v))
+			}
+		case "email":
+			s.Format = "email"
+		case "url", "uri", "http_url":
+			s.Format = "uri"
+		case "uuid", "uuid4":
+			s.Format = "uuid"
+		case "ip", "ipv4":
+			s.Format = "ipv4"
+		case "ipv6":
+			s.Format = "ipv6"
+		case "datetime":
+			s.Format = "date-time"
+		case "numeric", "number":
+			s.Pattern = `^[-+]?[0-9]*\.?[0-9]+$`
+		case "alpha":
+			s.Pattern = `^[a-zA-Z]+$`
+		case "alphanum":
+			s.Pattern = `^[a-zA-Z0-9]+$`
+		case "e164":
+			s.Pattern = `^\+[1-9]?[0-9]{7,14}$`
+		}
+	}
+}
+
+func enumValue(s *Schema, v string) any {
+	switch s.Type {
+	case "integer":
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
+
+// bound sets the lower or upper bound matching the schema type, the length for strings and arrays
+func bound(s *Schema, param string, lower, exclusive bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch s.Type {
+	case "integer", "number":
+		if lower {
+			s.Minimum, s.ExclusiveMinimum = &n, exclusive
+		} else {
+			s.Maximum, s.ExclusiveMaximum = &n, exclusive
+		}
+		return
+	}
+	if n < 0 {
+		return
+	}
+	l := uint64(n)
+	if exclusive && lower {
+		l++
+	} else if exclusive && l > 0 {
+		l--
+	}
+	switch s.Type {
+	case "string":
+		if lower {
+			s.MinLength = &l
+		} else {
+			s.MaxLength = &l
+		}
+	case "array":
+		if lower {
+			s.MinItems = &l
+		} else {
+			s.MaxItems = &l
+		}
+	}
+}
@@ -1,61 +1,36 @@
 package prometheus
 
 import (
+	"io"
 	"strconv"
-	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/skirrund/gcloud/server/metrics"
 )
 
 const (
-	metricsPath = "/metrics"
-	faviconPath = "/favicon.ico"
+	metricsPath      = "/metrics"
+	faviconPath      = "/favicon.ico"
+	DefaultNamespace = "http_server"
 )
 
-var (
-	// httpHistogram prometheus 模型
-	httpHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-		Namespace:   "http_server",
-		Subsystem:   "",
-		Name:        "requests_seconds",
-		Help:        "Histogram of response latency (seconds) of http handlers.",
-		ConstLabels: nil,
-		Buckets:     nil,
-	}, []string{"method", "code", "uri"})
-)
-
-// init 初始化prometheus模型
-func init() {
-	prometheus.MustRegister(httpHistogram)
-}
-
-// handlerPath 定义采样路由struct
-type handlerPath struct {
-	sync.Map
-}
-
-// get 获取path
-func (hp *handlerPath) get(handler string) string {
-	v, ok := hp.Load(handler)
-	if !ok {
-		return ""
-	}
-	return v.(string)
-}
-
-// set 保存path到sync.Map
-func (hp *handlerPath) set(ri gin.RouteInfo) {
-	hp.Store(ri.Handler, ri.Path)
-}
+// DefaultSizeBuckets spans 100B to about 10MB
+var DefaultSizeBuckets = prometheus.ExponentialBuckets(100, 10, 6)
 
 // GinPrometheus gin调用Prometheus的struct
 type GinPrometheus struct {
-	engine  *gin.Engine
-	ignored map[string]bool
-	pathMap *handlerPath
-	updated bool
+	engine      *gin.Engine
+	ignored     map[string]bool
+	namespace   string
+	buckets     []float64
+	sizeBuckets []float64
+
+	latency      *prometheus.HistogramVec
+	inFlight     prometheus.Gauge
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
 }
 
 type Option func(*GinPrometheus)
@@ -69,6 +44,33 @@ func Ignore(path ...string) Option {
 	}
 }
 
+// Namespace replaces DefaultNamespace, the prefix of every metric name
+func Namespace(ns string) Option {
+	return func(gp *GinPrometheus) {
+		if len(ns) > 0 {
+			gp.namespace = ns
+		}
+	}
+}
+
+// Buckets of the latency histogram in seconds, prometheus.DefBuckets when empty
+func Buckets(b ...float64) Option {
+	return func(gp *GinPrometheus) {
+		if len(b) > 0 {
+			gp.buckets = b
+		}
+	}
+}
+
+// SizeBuckets of the request and response size histograms in bytes, DefaultSizeBuckets when empty
+func SizeBuckets(b ...float64) Option {
+	return func(gp *GinPrometheus) {
+		if len(b) > 0 {
+			gp.sizeBuckets = b
+		}
+	}
+}
+
 // New new gin prometheus
 func New(e *gin.Engine, options ...Option) *GinPrometheus {
 	if e == nil {
@@ -81,42 +83,75 @@ func New(e *gin.Engine, options ...Option) *GinPrometheus {
 			metricsPath: true,
 			faviconPath: true,
 		},
-		pathMap: &handlerPath{},
+		namespace:   DefaultNamespace,
+		sizeBuckets: DefaultSizeBuckets,
 	}
 
 	for _, o := range options {
 		o(gp)
 	}
+	gp.latency = metrics.Register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: gp.namespace,
+		Name:      "requests_seconds",
+		Help:      "Histogram of response latency (seconds) of http handlers.",
+		Buckets:   gp.buckets,
+	}, []string{"method", "code", "uri"}))
+	gp.inFlight = metrics.Register(prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: gp.namespace,
+		Name:      "requests_in_flight",
+		Help:      "Requests currently being served.",
+	}))
+	gp.requestSize = metrics.Register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: gp.namespace,
+		Name:      "request_size_bytes",
+		Help:      "Histogram of request body sizes read by http handlers.",
+		Buckets:   gp.sizeBuckets,
+	}, []string{"method", "uri"}))
+	gp.responseSize = metrics.Register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: gp.namespace,
+		Name:      "response_size_bytes",
+		Help:      "Histogram of response body sizes as written to the client.",
+		Buckets:   gp.sizeBuckets,
+	}, []string{"method", "code", "uri"}))
 	return gp
 }
 
-// updatePath 更新path
-func (gp *GinPrometheus) updatePath() {
-	gp.updated = true
-	for _, ri := range gp.engine.Routes() {
-		gp.pathMap.set(ri)
-	}
+// countingBody counts the request bytes the handler actually reads, chunked bodies included
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
 }
 
 // Middleware set gin middleware
 func (gp *GinPrometheus) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if !gp.updated {
-			gp.updatePath()
-		}
 		// 过滤请求
-		if gp.ignored[c.Request.URL.String()] {
+		if gp.ignored[c.Request.URL.Path] {
 			c.Next()
 			return
 		}
 
 		start := time.Now()
+		gp.inFlight.Inc()
+		defer gp.inFlight.Dec()
+		body := &countingBody{ReadCloser: c.Request.Body}
+		if c.Request.Body != nil {
+			c.Request.Body = body
+		}
 		c.Next()
 
-		httpHistogram.WithLabelValues(
-			c.Request.Method,
-			strconv.Itoa(c.Writer.Status()),
-			gp.pathMap.get(c.HandlerName()),
-		).Observe(time.Since(start).Seconds())
+		// the route pattern is resolved per request, so routes added at any time are labelled
+		uri := c.FullPath()
+		method := c.Request.Method
+		code := strconv.Itoa(c.Writer.Status())
+		gp.latency.WithLabelValues(method, code, uri).Observe(time.Since(start).Seconds())
+		gp.requestSize.WithLabelValues(method, uri).Observe(float64(body.n))
+		gp.responseSize.WithLabelValues(method, code, uri).Observe(float64(max(c.Writer.Size(), 0)))
 	}
 }
@@ -0,0 +1,89 @@
+package gin
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	gm "github.com/skirrund/gcloud/plugins/server/http/gin/middleware"
+	"github.com/skirrund/gcloud/server"
+	"github.com/skirrund/gcloud/server/ratelimit"
+)
+
+const (
+	RATELIMIT_ENABLED_KEY      = "server.ratelimit.enabled"
+	RATELIMIT_STORE_KEY        = "server.ratelimit.store"
+	RATELIMIT_REDIS_PREFIX_KEY = "server.ratelimit.redisPrefix"
+	// RATELIMIT_RULES_KEY maps rule names to rules, e.g. server.ratelimit.rules.login.path=/user/login
+	RATELIMIT_RULES_KEY = "server.ratelimit.rules"
+	RateLimitStoreLocal = "local"
+	RateLimitStoreRedis = "redis"
+)
+
+// RateLimitRulesFromEnv reads server.ratelimit.rules sorted by rule name
+func RateLimitRulesFromEnv() ([]gm.RateLimitRule, error) {
+	m := make(map[string]gm.RateLimitRule)
+	if err := env.GetInstance().UnmarshalKey(RATELIMIT_RULES_KEY, &m); err != nil {
+		return nil, err
+	}
+	rules := make([]gm.RateLimitRule, 0, len(m))
+	for name, r := range m {
+		r.Name = name
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules, nil
+}
+
+var (
+	// rateLimiter is the latest middleware built by RateLimitFromEnv, its rules follow the config
+	rateLimiter       atomic.Pointer[gm.RateLimiter]
+	rateLimitHookOnce sync.Once
+)
+
+// RateLimitFromEnv builds the rate limit middleware from server.ratelimit.* and reloads the rules on ConfigChangeEvent,
+// only the latest middleware is reloaded
+func RateLimitFromEnv() gin.HandlerFunc {
+	cfg := env.GetInstance()
+	var limiter ratelimit.Limiter
+	if cfg.GetStringWithDefault(RATELIMIT_STORE_KEY, RateLimitStoreLocal) == RateLimitStoreRedis {
+		// the client is resolved on the first request, redis may not be initialized yet
+		limiter = &redisLimiter{prefix: cfg.GetString(RATELIMIT_REDIS_PREFIX_KEY)}
+	} else {
+		limiter = ratelimit.NewLocal()
+	}
+	rules, err := RateLimitRulesFromEnv()
+	if err != nil {
+		logger.Error("[GIN] ratelimit rules error:", err.Error())
+	}
+	rl := gm.NewRateLimiter(limiter, rules)
+	rateLimiter.Store(rl)
+	rateLimitHookOnce.Do(func() {
+		server.RegisterEventHook(server.ConfigChangeEvent, reloadRateLimit)
+	})
+	return rl.Handler()
+}
+
+func reloadRateLimit(eventType server.EventName, eventInfo any) error {
+	rl := rateLimiter.Load()
+	if rl == nil {
+		return nil
+	}
+	rules, err := RateLimitRulesFromEnv()
+	if err != nil {
+		logger.Error("[GIN] ratelimit rules reload error:", err.Error())
+		return err
+	}
+	rl.Update(rules)
+	return nil
+}
+
+func registerRateLimit(s *gin.Engine) {
+	if env.GetInstance().GetBool(RATELIMIT_ENABLED_KEY) {
+		logger.Info("[GIN] ratelimit enabled")
+		s.Use(RateLimitFromEnv())
+	}
+}
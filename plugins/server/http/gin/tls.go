@@ -0,0 +1,71 @@
+package gin
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/server"
+)
+
+// certReloader serves the current certificate and swaps it in place on CertRenewEvent
+type certReloader struct {
+	mu       sync.Mutex
+	certFile string
+	keyFile  string
+	cert     atomic.Pointer[tls.Certificate]
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{}
+	if err := r.load(certFile, keyFile); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load swaps in the pair and remembers its files, a pair that fails to load leaves the last good one in place
+func (r *certReloader) load(certFile, keyFile string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(certFile) == 0 || len(keyFile) == 0 {
+		certFile, keyFile = r.certFile, r.keyFile
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	r.certFile, r.keyFile = certFile, keyFile
+	r.cert.Store(&cert)
+	return nil
+}
+
+func (r *certReloader) onRenew(ctx context.Context, info any) error {
+	ci, _ := info.(server.CertRenewInfo)
+	if err := r.load(ci.CertFile, ci.KeyFile); err != nil {
+		logger.Error("[GIN] reload certificate error:", err.Error())
+		return err
+	}
+	r.mu.Lock()
+	certFile := r.certFile
+	r.mu.Unlock()
+	logger.Info("[GIN] certificate reloaded:", certFile)
+	return nil
+}
+
+// watch subscribes to CertRenewEvent and returns the unsubscribe function
+func (r *certReloader) watch() func() {
+	sub := server.OnEvent(server.CertRenewEvent, r.onRenew)
+	return sub.Unsubscribe
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := r.cert.Load()
+	if cert == nil {
+		return nil, errors.New("no certificate loaded")
+	}
+	return cert, nil
+}
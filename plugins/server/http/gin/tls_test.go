@@ -0,0 +1,113 @@
+package gin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/skirrund/gcloud/server"
+)
+
+// writeCert writes a self-signed pair for name into dir
+func writeCert(t *testing.T, dir, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600)
+	return certFile, keyFile
+}
+
+func TestCertReload(t *testing.T) {
+	dir := t.TempDir()
+	oldCert, oldKey := writeCert(t, dir, "old")
+	newCert, newKey := writeCert(t, dir, "new")
+	r, err := newCertReloader(oldCert, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.watch()()
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	ts.TLS = &tls.Config{GetCertificate: r.GetCertificate}
+	ts.StartTLS()
+	defer ts.Close()
+	served := func() string {
+		// with SNI the handshake asks GetCertificate instead of the certificate of httptest
+		conn, err := tls.Dial("tcp", ts.Listener.Addr().String(), &tls.Config{ServerName: "gcloud.test", InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+	if cn := served(); cn != "old" {
+		t.Fatalf("served %s", cn)
+	}
+	renew := func(info any) error {
+		return server.EmitEventSync(context.Background(), server.CertRenewEvent, info)
+	}
+	if err := renew(server.CertRenewInfo{CertFile: newCert, KeyFile: newKey}); err != nil {
+		t.Fatal(err)
+	}
+	if cn := served(); cn != "new" {
+		t.Fatalf("served %s after renew", cn)
+	}
+	if err := renew(server.CertRenewInfo{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: newKey}); err == nil {
+		t.Fatal("missing certificate loaded")
+	}
+	// a failed renew keeps the last good files for the next reload
+	if err := renew(nil); err != nil {
+		t.Fatal(err)
+	}
+	if cn := served(); cn != "new" {
+		t.Fatalf("served %s after failed renew", cn)
+	}
+}
+
+func TestNewHTTPServerTimeouts(t *testing.T) {
+	srv, h2s := newHTTPServer(server.Options{}, nil)
+	if srv.ReadTimeout != DefaultReadTimeout || srv.WriteTimeout != DefaultWriteTimeout ||
+		h2s.IdleTimeout != DefaultH2IdleTimeout || h2s.MaxConcurrentStreams != DefaultMaxConcurrentStreams {
+		t.Fatalf("defaults: %+v %+v", srv, h2s)
+	}
+	srv, h2s = newHTTPServer(server.Options{
+		ReadTimeout:          time.Second,
+		ReadHeaderTimeout:    2 * time.Second,
+		WriteTimeout:         3 * time.Second,
+		IdleTimeout:          4 * time.Second,
+		MaxHeaderBytes:       1024,
+		MaxConcurrentStreams: 8,
+	}, nil)
+	if srv.ReadTimeout != time.Second || srv.ReadHeaderTimeout != 2*time.Second || srv.WriteTimeout != 3*time.Second ||
+		srv.IdleTimeout != 4*time.Second || srv.MaxHeaderBytes != 1024 || h2s.IdleTimeout != 4*time.Second || h2s.MaxConcurrentStreams != 8 {
+		t.Fatalf("options: %+v %+v", srv, h2s)
+	}
+}
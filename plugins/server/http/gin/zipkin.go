@@ -0,0 +1,22 @@
+package gin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skirrund/gcloud/bootstrap/env"
+	"github.com/skirrund/gcloud/logger"
+	gm "github.com/skirrund/gcloud/plugins/server/http/gin/middleware"
+	"github.com/skirrund/gcloud/plugins/zipkin"
+)
+
+// registerZipkin traces the requests when zipkin.url is set, it must run after TraceMiddleware is installed
+func registerZipkin(s *gin.Engine) {
+	if len(env.GetInstance().GetString(env.ZIPKIN_URL_KEY)) == 0 {
+		return
+	}
+	t := zipkin.GetTracer()
+	if t == nil {
+		return
+	}
+	logger.Info("[GIN] zipkin enabled")
+	s.Use(gm.OpenTracing(t))
+}
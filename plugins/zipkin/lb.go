@@ -0,0 +1,79 @@
+package zipkin
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/server/request"
+	"github.com/skirrund/gcloud/server/response"
+)
+
+// LbInterceptor records every attempt of the lb pool as a client span with the chosen instance as peer,
+// a child of the span in the request context. The B3 headers are added to the request headers.
+func LbInterceptor(req *request.Request, instance string, next func(req *request.Request) (*response.Response, error)) (*response.Response, error) {
+	t := zkTracer
+	if t == nil {
+		return next(req)
+	}
+	method := req.Method
+	if len(method) == 0 {
+		method = http.MethodGet
+	}
+	opts := []opentracing.StartSpanOption{ext.SpanKindRPCClient}
+	if req.Context != nil {
+		if parent := opentracing.SpanFromContext(req.Context); parent != nil {
+			opts = append(opts, opentracing.ChildOf(parent.Context()))
+		}
+	}
+	name := method
+	if len(req.Path) > 0 {
+		name += " " + req.Path
+	}
+	span := t.StartSpan(name, opts...)
+	defer span.Finish()
+	ext.HTTPMethod.Set(span, method)
+	ext.HTTPUrl.Set(span, req.Url)
+	if len(req.ServiceName) > 0 {
+		ext.PeerService.Set(span, req.ServiceName)
+	}
+	setPeer(span, instance)
+	if req.Headers == nil {
+		req.Headers = make(map[string]string)
+	}
+	if err := t.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.TextMapCarrier(req.Headers)); err != nil {
+		logger.WarnContext(req.Context, "[zipkin] inject error:", err.Error())
+	}
+	resp, err := next(req)
+	if resp != nil && resp.StatusCode > 0 {
+		ext.HTTPStatusCode.Set(span, uint16(resp.StatusCode))
+	}
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogKV("error", err.Error())
+	}
+	return resp, err
+}
+
+// setPeer tags the host and port of instance, zipkin turns them into the remote endpoint
+func setPeer(span opentracing.Span, instance string) {
+	host, port, err := net.SplitHostPort(instance)
+	if err != nil {
+		host = instance
+	}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 0 {
+			ext.PeerHostname.Set(span, host)
+		}
+	} else if ip.To4() != nil {
+		ext.PeerHostIPv4.SetString(span, host)
+	} else {
+		ext.PeerHostIPv6.Set(span, host)
+	}
+	if p, err := strconv.ParseUint(port, 10, 16); err == nil {
+		ext.PeerPort.Set(span, uint16(p))
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"errors"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/server"
+	"github.com/skirrund/gcloud/server/lb"
 	"github.com/skirrund/gcloud/utils"
 
 	"github.com/skirrund/gcloud/bootstrap/env"
@@ -47,16 +50,43 @@ func initZipkinTracer() error {
 		logger.Fatal("[zipkin] unable to create local endpoint:", err, ",serviceName:", serviceName, ",", addr)
 		return err
 	}
-	nativeTracer, err := zipkin.NewTracer(zkReporter, zipkin.WithTraceID128Bit(true), zipkin.WithLocalEndpoint(endpoint))
+	nativeTracer, err := zipkin.NewTracer(zkReporter, zipkin.WithTraceID128Bit(true), zipkin.WithLocalEndpoint(endpoint), zipkin.WithSampler(newSampler()))
 	if err != nil {
 		logger.Fatal("[zipkin] unable to create tracer: ", err)
 		return err
 	}
 	zkTracer = zkOt.Wrap(nativeTracer)
 	opentracing.SetGlobalTracer(zkTracer)
+	lb.GetInstance().AddInterceptor(LbInterceptor)
+	// the reporter sends spans in batches, flush them before the process exits
+	server.RegisterEventHook(server.ShutdownEvent, func(eventType server.EventName, eventInfo any) error {
+		return Close()
+	})
 	return nil
 }
 
+// newSampler samples the share zipkin.sampleRate of the new traces, all of them when unset.
+// Requests whose B3 headers carry a sampling decision keep it.
+func newSampler() zipkin.Sampler {
+	cfg := env.GetInstance()
+	if cfg.Get(env.ZIPKIN_SAMPLE_RATE_KEY) == nil {
+		return zipkin.AlwaysSample
+	}
+	rate := cfg.GetFloat64(env.ZIPKIN_SAMPLE_RATE_KEY)
+	if rate >= 1 {
+		return zipkin.AlwaysSample
+	}
+	if rate <= 0 {
+		return zipkin.NeverSample
+	}
+	sampler, err := zipkin.NewBoundarySampler(rate, time.Now().UnixNano())
+	if err != nil {
+		logger.Error("[zipkin] sampler error:", err.Error(), ",rate:", rate)
+		return zipkin.AlwaysSample
+	}
+	return sampler
+}
+
 func WrapHttp(request *http.Request, host string) (opentracing.Span, error) {
 	var span opentracing.Span
 	if zkTracer != nil {
@@ -81,8 +111,14 @@ func WrapHttp(request *http.Request, host string) (opentracing.Span, error) {
 	return nil, errors.New("no span")
 }
 
-func Close() {
-	if zkReporter != nil {
-		zkReporter.Close()
+// Close flushes the pending spans, it runs on ShutdownEvent once the tracer is created
+func Close() error {
+	if zkReporter == nil {
+		return nil
+	}
+	err := zkReporter.Close()
+	if err != nil {
+		logger.Error("[zipkin] close reporter error:", err.Error())
 	}
+	return err
 }
@@ -1,6 +1,7 @@
 package registry
 
 import (
+	"context"
 	"strconv"
 )
 
@@ -14,6 +15,12 @@ type IRegistry interface {
 	SelectInstances(serviceName string) ([]*Instance, error)
 }
 
+// Pinger is implemented by registries that can check their connection to the registry server,
+// Bootstrap reports the registry component down while Ping fails
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
 type Instance struct {
 	Ip       string
 	Port     uint64
@@ -31,12 +31,14 @@ var PHONE_OR_PASSWORD_ERROR = Msginfo{Code: "400007", Message: "手机号或密
 var PHONE_ERROR_FORMAT = Msginfo{Code: "400008", Message: "手机号格式不正确"}
 var VALIDATE_API_ERROR = Msginfo{Code: "400010", Message: "数据校验不合法"}
 var REQUEST_FREQUENTLY_ERROR = Msginfo{Code: "400011", Message: "请求过于频繁"}
+var IDEMPOTENCY_IN_PROGRESS = Msginfo{Code: "400012", Message: "请求正在处理中,请勿重复提交"}
 var EXCEPTION = Msginfo{Code: ERROR, Message: "系统繁忙,请稍后重试"}
 var COMMON_EXCEPTION = Msginfo{Code: "500001", Message: "消息处理异常"}
 var DB_INSERT_EXCEPTION = Msginfo{Code: "500002", Message: "数据插入异常"}
 var DB_UPDATE_EXCEPTION = Msginfo{Code: "500003", Message: "数据更新异常"}
 var DB_SELECT_EXCEPTION = Msginfo{Code: "500004", Message: "数据查询异常"}
 var DB_KEY_DUPLICATE = Msginfo{Code: "500011", Message: "主键或唯一性约束冲突"}
+var TIMEOUT_EXCEPTION = Msginfo{Code: "504000", Message: "请求超时,请稍后重试"}
 var SMS_TEMPLATE_ERROR = Msginfo{Code: "4B2001", Message: "无效的短信模板"}
 
 var FLOW_EXCEPTION = Msginfo{Code: "503000", Message: "请求过于拥挤，请稍候重试"}
@@ -0,0 +1,64 @@
+// Package concurrency bounds the number of requests processed at the same time.
+package concurrency
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+type Priority int
+
+const (
+	// PriorityCritical bypasses the limiter, e.g. health probes and metrics scrapes
+	PriorityCritical Priority = iota
+	PriorityNormal
+	// PriorityLow is shed first, once LowRatio of the limit is in use
+	PriorityLow
+)
+
+const DefaultLowRatio = 0.9
+
+type Limiter struct {
+	algorithm Algorithm
+	inflight  atomic.Int64
+	// LowRatio is the share of the limit PriorityLow requests may use
+	LowRatio float64
+}
+
+func New(algorithm Algorithm) *Limiter {
+	return &Limiter{algorithm: algorithm, LowRatio: DefaultLowRatio}
+}
+
+// Release ends a request admitted by Acquire, dropped reports that it failed because of overload
+type Release func(dropped bool)
+
+func noopRelease(bool) {}
+
+// Acquire admits a request or reports false when it should be shed
+func (l *Limiter) Acquire(p Priority) (Release, bool) {
+	if p == PriorityCritical {
+		return noopRelease, true
+	}
+	limit := int64(l.algorithm.Limit())
+	if p == PriorityLow {
+		limit = int64(float64(limit) * l.LowRatio)
+	}
+	inflight := l.inflight.Add(1)
+	if inflight > limit {
+		l.inflight.Add(-1)
+		return nil, false
+	}
+	start := time.Now()
+	return func(dropped bool) {
+		l.inflight.Add(-1)
+		l.algorithm.OnSample(time.Since(start), int(inflight), dropped)
+	}, true
+}
+
+func (l *Limiter) Inflight() int {
+	return int(l.inflight.Load())
+}
+
+func (l *Limiter) Limit() int {
+	return l.algorithm.Limit()
+}
@@ -0,0 +1,57 @@
+package concurrency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := New(Fixed(2))
+	r1, ok1 := l.Acquire(PriorityNormal)
+	_, ok2 := l.Acquire(PriorityNormal)
+	if !ok1 || !ok2 {
+		t.Fatal("requests within the limit must be admitted")
+	}
+	if _, ok := l.Acquire(PriorityNormal); ok {
+		t.Fatal("request over the limit must be shed")
+	}
+	if _, ok := l.Acquire(PriorityCritical); !ok {
+		t.Fatal("critical requests bypass the limit")
+	}
+	r1(false)
+	if _, ok := l.Acquire(PriorityLow); ok {
+		t.Fatal("low priority is shed before the limit is reached")
+	}
+	if l.Inflight() != 1 {
+		t.Fatalf("inflight %d", l.Inflight())
+	}
+}
+
+func TestAIMD(t *testing.T) {
+	a := NewAIMD(10, 2, 20, 100*time.Millisecond)
+	a.OnSample(10*time.Millisecond, 8, false)
+	if a.Limit() != 11 {
+		t.Fatalf("expected additive increase, got %d", a.Limit())
+	}
+	a.OnSample(200*time.Millisecond, 8, false)
+	if a.Limit() != 9 {
+		t.Fatalf("expected multiplicative decrease, got %d", a.Limit())
+	}
+}
+
+func TestGradient(t *testing.T) {
+	g := NewGradient(20, 5, 100)
+	for i := 0; i < 50; i++ {
+		g.OnSample(10*time.Millisecond, g.Limit(), false)
+	}
+	grown := g.Limit()
+	if grown <= 20 {
+		t.Fatalf("steady latency should grow the limit, got %d", grown)
+	}
+	for i := 0; i < 50; i++ {
+		g.OnSample(100*time.Millisecond, g.Limit(), false)
+	}
+	if g.Limit() >= grown {
+		t.Fatalf("rising latency should shrink the limit, got %d from %d", g.Limit(), grown)
+	}
+}
@@ -0,0 +1,123 @@
+package concurrency
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Algorithm decides how many requests may be in flight
+type Algorithm interface {
+	Limit() int
+	// OnSample reports a finished request: its latency, the in-flight count when it started,
+	// and whether it was dropped (timed out or failed because of overload)
+	OnSample(rtt time.Duration, inflight int, dropped bool)
+}
+
+// Fixed is a static limit
+type Fixed int
+
+func (f Fixed) Limit() int {
+	return int(f)
+}
+
+func (f Fixed) OnSample(time.Duration, int, bool) {}
+
+// AIMD grows the limit by one while the limit is in use and cuts it by BackoffRatio
+// whenever a request is dropped or slower than Timeout
+type AIMD struct {
+	mu           sync.Mutex
+	limit        int
+	MinLimit     int
+	MaxLimit     int
+	BackoffRatio float64
+	Timeout      time.Duration
+}
+
+func NewAIMD(initial, min, max int, timeout time.Duration) *AIMD {
+	return &AIMD{limit: initial, MinLimit: min, MaxLimit: max, BackoffRatio: 0.9, Timeout: timeout}
+}
+
+func (a *AIMD) Limit() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.limit
+}
+
+func (a *AIMD) OnSample(rtt time.Duration, inflight int, dropped bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if dropped || (a.Timeout > 0 && rtt > a.Timeout) {
+		a.limit = int(float64(a.limit) * a.BackoffRatio)
+	} else if inflight*2 >= a.limit {
+		a.limit++
+	}
+	a.limit = clamp(a.limit, a.MinLimit, a.MaxLimit)
+}
+
+// Gradient compares the latest latency with a long term average: as queueing builds up the
+// ratio drops below one and so does the limit, a sqrt(limit) headroom lets it probe upwards.
+type Gradient struct {
+	mu        sync.Mutex
+	limit     float64
+	longRtt   float64
+	samples   int
+	MinLimit  int
+	MaxLimit  int
+	Smoothing float64
+	// Tolerance is how much slower than the average a request may be before the limit shrinks
+	Tolerance float64
+	// Window is the number of samples averaged into the long term latency
+	Window int
+}
+
+func NewGradient(initial, min, max int) *Gradient {
+	return &Gradient{limit: float64(initial), MinLimit: min, MaxLimit: max, Smoothing: 0.2, Tolerance: 1.5, Window: 600}
+}
+
+func (g *Gradient) Limit() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return int(g.limit)
+}
+
+func (g *Gradient) OnSample(rtt time.Duration, inflight int, dropped bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	short := float64(rtt)
+	if short <= 0 {
+		return
+	}
+	// exponential average that warms up as a plain mean
+	if g.samples < g.Window {
+		g.samples++
+	}
+	g.longRtt += (short - g.longRtt) / float64(g.samples)
+	// don't grow a limit the traffic isn't using
+	if !dropped && float64(inflight) < g.limit/2 {
+		return
+	}
+	gradient := math.Max(0.5, math.Min(1, g.Tolerance*g.longRtt/short))
+	if dropped {
+		gradient = 0.5
+	}
+	newLimit := g.limit*gradient + math.Sqrt(g.limit)
+	g.limit = g.limit*(1-g.Smoothing) + newLimit*g.Smoothing
+	g.limit = math.Max(g.limit, float64(max(g.MinLimit, 1)))
+	if g.MaxLimit > 0 {
+		g.limit = math.Min(g.limit, float64(g.MaxLimit))
+	}
+}
+
+func clamp(v, min, max int) int {
+	if min < 1 {
+		min = 1
+	}
+	if v < min {
+		return min
+	}
+	if max > 0 && v > max {
+		return max
+	}
+	return v
+}
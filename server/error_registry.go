@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/skirrund/gcloud/response"
+	"github.com/skirrund/gcloud/utils"
+)
+
+// ErrorMapping is how an error is answered
+type ErrorMapping struct {
+	Msginfo response.Msginfo
+	// Status is the HTTP status, see SetErrorStatus to override it per Msginfo code
+	Status int
+	// SubMsg is shown as SubMessage, it defaults to the error text for Internal mappings
+	SubMsg string
+	// Internal marks mappings whose SubMsg leaks implementation details and is hidden in production
+	Internal bool
+}
+
+// ErrorMatcher returns the mapping of err, it sees the whole chain and should use errors.As/errors.Is
+type ErrorMatcher func(err error) (ErrorMapping, bool)
+
+var errorRegistry = struct {
+	sync.RWMutex
+	matchers []ErrorMatcher
+	statuses map[string]int
+}{statuses: make(map[string]int)}
+
+func init() {
+	RegisterErrorMatcher(func(err error) (ErrorMapping, bool) {
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			return ErrorMapping{Msginfo: response.TIMEOUT_EXCEPTION, Status: http.StatusGatewayTimeout, Internal: true}, true
+		case errors.Is(err, context.Canceled):
+			return ErrorMapping{Msginfo: response.EXCEPTION, Status: StatusClientClosedRequest, Internal: true}, true
+		}
+		return ErrorMapping{}, false
+	})
+	RegisterErrorMatcher(func(err error) (ErrorMapping, bool) {
+		var e *Error
+		if !errors.As(err, &e) {
+			var ev Error
+			if !errors.As(err, &ev) {
+				return ErrorMapping{}, false
+			}
+			e = &ev
+		}
+		mi := response.Msginfo{Code: e.Code, Message: e.Msg}
+		if len(mi.Code) == 0 {
+			mi.Code = response.ERROR
+		}
+		if len(mi.Message) == 0 {
+			mi.Message = response.EXCEPTION.Message
+		}
+		return ErrorMapping{Msginfo: mi, Status: http.StatusOK, SubMsg: e.SubMsg}, true
+	})
+	RegisterErrorMatcher(func(err error) (ErrorMapping, bool) {
+		var e *utils.Error
+		if !errors.As(err, &e) {
+			return ErrorMapping{}, false
+		}
+		m := ErrorMapping{Msginfo: response.EXCEPTION, Status: http.StatusOK}
+		if e.Msginfo != nil {
+			m.Msginfo = *e.Msginfo
+		}
+		if e.Err != nil {
+			m.SubMsg = e.Err.Error()
+		}
+		return m, true
+	})
+}
+
+// StatusClientClosedRequest answers requests whose client went away
+const StatusClientClosedRequest = 499
+
+// RegisterErrorMatcher adds a matcher, matchers registered later are tried first
+func RegisterErrorMatcher(m ErrorMatcher) {
+	errorRegistry.Lock()
+	defer errorRegistry.Unlock()
+	errorRegistry.matchers = append(errorRegistry.matchers, m)
+}
+
+// RegisterError maps every error matching target with errors.Is
+func RegisterError(target error, mapping ErrorMapping) {
+	RegisterErrorMatcher(func(err error) (ErrorMapping, bool) {
+		return mapping, errors.Is(err, target)
+	})
+}
+
+// SetErrorStatus overrides the HTTP status of every mapping with the given Msginfo code
+func SetErrorStatus(code string, status int) {
+	errorRegistry.Lock()
+	defer errorRegistry.Unlock()
+	errorRegistry.statuses[code] = status
+}
+
+// MapError finds the mapping of err, unknown errors are EXCEPTION with status 500
+func MapError(err error) ErrorMapping {
+	errorRegistry.RLock()
+	defer errorRegistry.RUnlock()
+	m := ErrorMapping{Msginfo: response.EXCEPTION, Status: http.StatusInternalServerError, Internal: true}
+	for i := len(errorRegistry.matchers) - 1; i >= 0; i-- {
+		if mm, ok := errorRegistry.matchers[i](err); ok {
+			m = mm
+			break
+		}
+	}
+	if len(m.SubMsg) == 0 && m.Internal {
+		m.SubMsg = err.Error()
+	}
+	if m.Status == 0 {
+		m.Status = http.StatusOK
+	}
+	if s, ok := errorRegistry.statuses[m.Msginfo.Code]; ok {
+		m.Status = s
+	}
+	return m
+}
@@ -0,0 +1,244 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+
+	"github.com/skirrund/gcloud/logger"
+)
+
+// Handler receives events of type T published on a topic
+type Handler[T any] func(ctx context.Context, event T) error
+
+// ErrorHandler is called with the error or recovered panic of a subscriber
+type ErrorHandler func(topic string, err error)
+
+type subscriber struct {
+	id      uint64
+	deliver func(ctx context.Context, payload any) (bool, error)
+	onError ErrorHandler
+	first   bool
+}
+
+type asyncEvent struct {
+	ctx     context.Context
+	payload any
+}
+
+type topicQueue struct {
+	events  []asyncEvent
+	running bool
+}
+
+// Bus dispatches events to typed subscribers.
+//
+// Subscribers of a topic are called one after another in subscription order
+// (subscribers added with First run before the others). Publish runs them in the
+// caller goroutine; PublishAsync queues the event and a per-topic dispatcher
+// delivers queued events in publish order.
+type Bus struct {
+	mu     sync.RWMutex
+	topics map[string][]*subscriber
+	nextID atomic.Uint64
+	qmu    sync.Mutex
+	queues map[string]*topicQueue
+	wg     sync.WaitGroup
+}
+
+var defaultBus = New()
+
+func New() *Bus {
+	return &Bus{
+		topics: make(map[string][]*subscriber),
+		queues: make(map[string]*topicQueue),
+	}
+}
+
+func Default() *Bus {
+	return defaultBus
+}
+
+type SubscribeOption func(*subscriber)
+
+// First places the subscriber ahead of the ones already registered
+func First() SubscribeOption {
+	return func(s *subscriber) {
+		s.first = true
+	}
+}
+
+// OnError overrides the default error handler, which logs the error
+func OnError(h ErrorHandler) SubscribeOption {
+	return func(s *subscriber) {
+		s.onError = h
+	}
+}
+
+// Subscription is the handle returned by Subscribe
+type Subscription struct {
+	bus   *Bus
+	topic string
+	id    uint64
+	once  sync.Once
+}
+
+// Unsubscribe removes the subscriber, events already being delivered are not interrupted
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.bus.remove(s.topic, s.id)
+	})
+}
+
+// Subscribe registers h for events on topic whose payload is assignable to T.
+// Events with a payload of another type are skipped for this subscriber.
+func Subscribe[T any](b *Bus, topic string, h Handler[T], opts ...SubscribeOption) *Subscription {
+	isInterface := reflect.TypeFor[T]().Kind() == reflect.Interface
+	s := &subscriber{
+		id:      b.nextID.Add(1),
+		onError: logError,
+		deliver: func(ctx context.Context, payload any) (bool, error) {
+			v, ok := payload.(T)
+			if !ok && !(payload == nil && isInterface) {
+				return false, nil
+			}
+			return true, h(ctx, v)
+		},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs := b.topics[topic]
+	list := make([]*subscriber, 0, len(subs)+1)
+	if s.first {
+		list = append(append(list, s), subs...)
+	} else {
+		list = append(append(list, subs...), s)
+	}
+	b.topics[topic] = list
+	return &Subscription{bus: b, topic: topic, id: s.id}
+}
+
+// Publish delivers event synchronously and returns the joined subscriber errors
+func Publish[T any](ctx context.Context, b *Bus, topic string, event T) error {
+	return b.dispatch(ctx, topic, event)
+}
+
+// PublishAsync queues event for delivery, the context is kept for its values only
+func PublishAsync[T any](ctx context.Context, b *Bus, topic string, event T) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	b.enqueue(topic, asyncEvent{ctx: context.WithoutCancel(ctx), payload: event})
+}
+
+// HasSubscribers reports whether anyone listens on topic
+func (b *Bus) HasSubscribers(topic string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.topics[topic]) > 0
+}
+
+// Wait blocks until all queued async events are delivered or ctx is done
+func (b *Bus) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Bus) remove(topic string, id uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs := b.topics[topic]
+	list := make([]*subscriber, 0, len(subs))
+	for _, s := range subs {
+		if s.id != id {
+			list = append(list, s)
+		}
+	}
+	if len(list) == 0 {
+		delete(b.topics, topic)
+	} else {
+		b.topics[topic] = list
+	}
+}
+
+func (b *Bus) dispatch(ctx context.Context, topic string, payload any) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	b.mu.RLock()
+	subs := b.topics[topic]
+	b.mu.RUnlock()
+	var errs []error
+	for _, s := range subs {
+		if err := s.call(ctx, topic, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *subscriber) call(ctx context.Context, topic string, payload any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+		if err != nil && s.onError != nil {
+			s.onError(topic, err)
+		}
+	}()
+	_, err = s.deliver(ctx, payload)
+	return err
+}
+
+func (b *Bus) enqueue(topic string, ev asyncEvent) {
+	b.qmu.Lock()
+	defer b.qmu.Unlock()
+	q, ok := b.queues[topic]
+	if !ok {
+		q = &topicQueue{}
+		b.queues[topic] = q
+	}
+	q.events = append(q.events, ev)
+	if !q.running {
+		q.running = true
+		b.wg.Add(1)
+		go b.drain(topic, q)
+	}
+}
+
+func (b *Bus) drain(topic string, q *topicQueue) {
+	defer b.wg.Done()
+	for {
+		b.qmu.Lock()
+		if len(q.events) == 0 {
+			q.running = false
+			b.qmu.Unlock()
+			return
+		}
+		ev := q.events[0]
+		q.events[0] = asyncEvent{}
+		q.events = q.events[1:]
+		b.qmu.Unlock()
+		b.dispatch(ev.ctx, topic, ev.payload)
+	}
+}
+
+func logError(topic string, err error) {
+	logger.Error("[eventbus] error on '", topic, "' subscriber:", err.Error())
+}
@@ -0,0 +1,89 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTypedSubscribers(t *testing.T) {
+	b := New()
+	var got []string
+	Subscribe(b, "t", func(ctx context.Context, e string) error {
+		got = append(got, "string:"+e)
+		return nil
+	})
+	Subscribe(b, "t", func(ctx context.Context, e any) error {
+		got = append(got, "any")
+		return nil
+	})
+	Subscribe(b, "t", func(ctx context.Context, e int) error {
+		got = append(got, "int")
+		return nil
+	}, First())
+	if err := Publish(context.Background(), b, "t", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Publish[any](context.Background(), b, "t", 1); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"string:x", "any", "int", "any"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v,want %v", got, want)
+	}
+}
+
+func TestErrorsPanicsAndUnsubscribe(t *testing.T) {
+	b := New()
+	var handled []error
+	onErr := OnError(func(topic string, err error) { handled = append(handled, err) })
+	Subscribe(b, "t", func(ctx context.Context, e int) error { return errors.New("boom") }, onErr)
+	Subscribe(b, "t", func(ctx context.Context, e int) error { panic("oops") }, onErr)
+	calls := 0
+	sub := Subscribe(b, "t", func(ctx context.Context, e int) error {
+		calls++
+		return nil
+	})
+	if err := Publish(context.Background(), b, "t", 1); err == nil {
+		t.Fatal("expected joined error")
+	}
+	if len(handled) != 2 || calls != 1 {
+		t.Fatalf("handled=%v calls=%d", handled, calls)
+	}
+	sub.Unsubscribe()
+	Publish(context.Background(), b, "t", 1)
+	if calls != 1 {
+		t.Fatalf("unsubscribed handler called %d", calls)
+	}
+}
+
+func TestAsyncOrdering(t *testing.T) {
+	b := New()
+	var mu sync.Mutex
+	var got []int
+	Subscribe(b, "t", func(ctx context.Context, e int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, e)
+		return nil
+	})
+	for i := range 100 {
+		PublishAsync(context.Background(), b, "t", i)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := b.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range got {
+		if i != v {
+			t.Fatalf("out of order at %d: %v", i, got)
+		}
+	}
+	if len(got) != 100 {
+		t.Fatalf("delivered %d", len(got))
+	}
+}
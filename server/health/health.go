@@ -0,0 +1,193 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Kind int
+
+const (
+	KindLiveness Kind = 1 << iota
+	KindReadiness
+
+	KindBoth = KindLiveness | KindReadiness
+)
+
+type Status string
+
+const (
+	StatusUp   Status = "UP"
+	StatusDown Status = "DOWN"
+
+	DefaultTimeout = 3 * time.Second
+	shutdownName   = "shutdown"
+)
+
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// Check returns nil when the probed dependency is healthy
+type Check func(ctx context.Context) error
+
+type ComponentStatus struct {
+	Status   Status `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"durationMs"`
+}
+
+type Report struct {
+	Status     Status                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+}
+
+type probe struct {
+	kind    Kind
+	check   Check
+	timeout time.Duration
+}
+
+type Option func(*probe)
+
+func Timeout(d time.Duration) Option {
+	return func(p *probe) {
+		p.timeout = d
+	}
+}
+
+type Registry struct {
+	mu           sync.RWMutex
+	probes       map[string]probe
+	shuttingDown atomic.Bool
+}
+
+var defaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{probes: make(map[string]probe)}
+}
+
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Register adds or replaces the probe with the given name
+func (r *Registry) Register(name string, kind Kind, check Check, opts ...Option) {
+	p := probe{kind: kind, check: check, timeout: DefaultTimeout}
+	for _, o := range opts {
+		o(&p)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.probes[name] = p
+}
+
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.probes, name)
+}
+
+// SetShuttingDown makes readiness fail so load balancers stop routing before connections are drained
+func (r *Registry) SetShuttingDown(v bool) {
+	r.shuttingDown.Store(v)
+}
+
+func (r *Registry) IsShuttingDown() bool {
+	return r.shuttingDown.Load()
+}
+
+func (r *Registry) Liveness(ctx context.Context) Report {
+	return r.run(ctx, KindLiveness)
+}
+
+func (r *Registry) Readiness(ctx context.Context) Report {
+	report := r.run(ctx, KindReadiness)
+	if r.IsShuttingDown() {
+		report.Status = StatusDown
+		report.Components[shutdownName] = ComponentStatus{Status: StatusDown, Error: ErrShuttingDown.Error()}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, kind Kind) Report {
+	r.mu.RLock()
+	probes := make(map[string]probe, len(r.probes))
+	for name, p := range r.probes {
+		if p.kind&kind != 0 {
+			probes[name] = p
+		}
+	}
+	r.mu.RUnlock()
+	report := Report{Status: StatusUp, Components: make(map[string]ComponentStatus, len(probes))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, p := range probes {
+		wg.Go(func() {
+			cs := runProbe(ctx, p)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[name] = cs
+			if cs.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		})
+	}
+	wg.Wait()
+	return report
+}
+
+func runProbe(ctx context.Context, p probe) (cs ComponentStatus) {
+	start := time.Now()
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- errors.New("probe panic")
+			}
+		}()
+		done <- p.check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	cs.Duration = time.Since(start).Milliseconds()
+	if err != nil {
+		cs.Status = StatusDown
+		cs.Error = err.Error()
+	} else {
+		cs.Status = StatusUp
+	}
+	return cs
+}
+
+// Register adds a probe to the default registry
+func Register(name string, kind Kind, check Check, opts ...Option) {
+	defaultRegistry.Register(name, kind, check, opts...)
+}
+
+func Unregister(name string) {
+	defaultRegistry.Unregister(name)
+}
+
+func SetShuttingDown(v bool) {
+	defaultRegistry.SetShuttingDown(v)
+}
+
+func Liveness(ctx context.Context) Report {
+	return defaultRegistry.Liveness(ctx)
+}
+
+func Readiness(ctx context.Context) Report {
+	return defaultRegistry.Readiness(ctx)
+}
@@ -0,0 +1,36 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadiness(t *testing.T) {
+	r := NewRegistry()
+	r.Register("ok", KindBoth, func(ctx context.Context) error { return nil })
+	r.Register("db", KindReadiness, func(ctx context.Context) error { return errors.New("down") })
+	r.Register("slow", KindReadiness, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, Timeout(10*time.Millisecond))
+
+	if rep := r.Liveness(context.Background()); rep.Status != StatusUp || len(rep.Components) != 1 {
+		t.Fatalf("liveness %+v", rep)
+	}
+	rep := r.Readiness(context.Background())
+	if rep.Status != StatusDown || rep.Components["db"].Status != StatusDown || rep.Components["slow"].Status != StatusDown {
+		t.Fatalf("readiness %+v", rep)
+	}
+
+	r.Unregister("db")
+	r.Unregister("slow")
+	if rep := r.Readiness(context.Background()); rep.Status != StatusUp {
+		t.Fatalf("readiness %+v", rep)
+	}
+	r.SetShuttingDown(true)
+	if rep := r.Readiness(context.Background()); rep.Status != StatusDown {
+		t.Fatalf("readiness during shutdown %+v", rep)
+	}
+}
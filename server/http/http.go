@@ -20,6 +20,7 @@ import (
 	"github.com/skirrund/gcloud/tracer"
 	"github.com/skirrund/gcloud/utils"
 	"github.com/skirrund/gcloud/utils/decimal"
+	"github.com/skirrund/gcloud/utils/redact"
 )
 
 const (
@@ -93,7 +94,7 @@ func getJSONData(params any) []byte {
 	} else {
 		body, _ := utils.Marshal(params)
 		if env.GetInstance().GetBool(HTTP_LOG_ENABLE_KEY) {
-			logger.Info("[http] getJSONData:", logger.GetLogStr(string(body)))
+			logger.Info("[http] getJSONData:", logger.GetLogStr(string(redact.JSON(body))))
 		}
 		reader = body
 	}
@@ -140,7 +141,7 @@ func getFormData(params map[string]any) []byte {
 	}
 	valuesStr := values.Encode()
 	if log {
-		logger.Info("[http] getFormData string:", valuesStr)
+		logger.Info("[http] getFormData string:", redact.Form(valuesStr))
 	}
 	return []byte(valuesStr)
 }
@@ -190,7 +191,7 @@ func getMultipartFormData(params map[string]any, files map[string]*request.File)
 			if len(val) > 0 {
 				err = bodyWriter.WriteField(k, val)
 				if log {
-					logger.Info("[http] getMultipartFormData:", k, ":", logger.GetLogStr(string(val)))
+					logger.Info("[http] getMultipartFormData:", k, ":", logger.GetLogStr(redact.Field(k, string(val))))
 				}
 			}
 		} else if val, ok := v.(*string); ok {
@@ -212,7 +213,7 @@ func getMultipartFormData(params map[string]any, files map[string]*request.File)
 				continue
 			}
 			if log {
-				logger.Info("[http] getMultipartFormData:", k, ":", logger.GetLogStr(string(str)))
+				logger.Info("[http] getMultipartFormData:", k, ":", logger.GetLogStr(redact.Field(k, string(str))))
 			}
 		}
 		if err != nil {
@@ -29,6 +29,9 @@ const (
 
 var defaultTransport *http.Transport
 
+// lbLog writes the per request logs under the name of server/lb, demote them with logger.demote: [lb]
+var lbLog = logger.Named("lb")
+
 //var h2cTransport *http2.Transport
 var transporth2c *http.Transport
 
@@ -175,6 +178,10 @@ func (NetHttpClient) Exec(req *request.Request) (r *gResp.Response, err error) {
 		return r, err
 	}
 	setHeader(doRequest.Header, headers)
+	span := startClientSpan(loggerCtx, doRequest)
+	defer func() {
+		endClientSpan(span, r.StatusCode, err)
+	}()
 	timeOut := req.TimeOut
 	if timeOut == 0 {
 		timeOut = default_timeout
@@ -201,7 +208,7 @@ func (NetHttpClient) Exec(req *request.Request) (r *gResp.Response, err error) {
 	r.ContentType = ct
 	proto := response.Proto
 	b, err := io.ReadAll(response.Body)
-	logger.InfoContext(loggerCtx, "[lb-http] reqUrl:", reqUrl, "=> response statusCode:", sc, " content-type:", ct, " proto:", proto)
+	lbLog.InfoContext(loggerCtx, "[lb-http] response", "url", reqUrl, "status", sc, "contentType", ct, "proto", proto)
 	r.Body = b
 	if err != nil {
 		logger.ErrorContext(loggerCtx, "[lb-http] response body read error:", reqUrl)
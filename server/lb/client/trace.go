@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/skirrund/gcloud/tracer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startClientSpan starts the client span of req and writes its traceparent and legacy trace id headers,
+// headers set by the caller are kept
+func startClientSpan(ctx context.Context, req *http.Request) trace.Span {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.URLFull(req.URL.String()),
+		semconv.ServerAddress(req.URL.Hostname()),
+	}
+	if port, err := strconv.Atoi(req.URL.Port()); err == nil {
+		attrs = append(attrs, semconv.ServerPort(port))
+	}
+	ctx, span := tracer.Start(ctx, req.Method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	tracer.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	if len(req.Header.Get(tracer.TraceIDKey)) == 0 {
+		if id, ok := tracer.GetTraceID(ctx).(string); ok {
+			req.Header.Set(tracer.TraceIDKey, id)
+		}
+	}
+	return span
+}
+
+func endClientSpan(span trace.Span, status int, err error) {
+	if status > 0 {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
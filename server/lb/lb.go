@@ -36,11 +36,19 @@ const (
 
 var once sync.Once
 
+// lbLog writes the per request logs, demote them with logger.demote: [lb]
+var lbLog = logger.Named("lb")
+
 type ServerPool struct {
-	Services sync.Map
-	client   client.HttpClient
+	Services     sync.Map
+	client       client.HttpClient
+	interceptors []Interceptor
 }
 
+// Interceptor wraps every attempt of Run, e.g. to trace it. instance is the "ip:port" of the chosen
+// instance, or the url host when the request is not load balanced.
+type Interceptor func(req *request.Request, instance string, next func(req *request.Request) (*response.Response, error)) (*response.Response, error)
+
 type service struct {
 	Instances []*registry.Instance
 	H2C       bool
@@ -61,10 +69,7 @@ func GetInstance() *ServerPool {
 		sp = &ServerPool{
 			client: lbClient.NetHttpClient{},
 		}
-		err := server.RegisterEventHook(server.RegistryChangeEvent, server.EventHook(sp.regChange))
-		if err != nil {
-			logger.Error("[LB]:", err)
-		}
+		server.OnEvent(server.RegistryChangeEvent, sp.regChange)
 	})
 	return sp
 }
@@ -77,15 +82,28 @@ func (s *ServerPool) GetHttpClient() client.HttpClient {
 	return s.client
 }
 
-func (s *ServerPool) regChange(eventType server.EventName, eventInfo any) error {
-	if eventType == server.RegistryChangeEvent {
-		logger.Info("[LB] registry change:", eventInfo)
-		if info, ok := eventInfo.(map[string][]*registry.Instance); ok {
-			for k, v := range info {
-				s.setService(k, v)
-			}
+// AddInterceptor appends interceptors, the first one added is the outermost.
+// Like SetHttpClient it is meant to be called before requests are sent.
+func (s *ServerPool) AddInterceptor(i ...Interceptor) {
+	s.interceptors = append(s.interceptors, i...)
+}
+
+func (s *ServerPool) do(req *request.Request, instance string) (*response.Response, error) {
+	call := s.client.Exec
+	for i := len(s.interceptors) - 1; i >= 0; i-- {
+		ic, next := s.interceptors[i], call
+		call = func(req *request.Request) (*response.Response, error) {
+			return ic(req, instance, next)
 		}
 	}
+	return call(req)
+}
+
+func (s *ServerPool) regChange(ctx context.Context, info map[string][]*registry.Instance) error {
+	logger.Info("[LB] registry change:", info)
+	for k, v := range info {
+		s.setService(k, v)
+	}
 	return nil
 }
 
@@ -109,7 +127,7 @@ func (s *ServerPool) setService(name string, instances []*registry.Instance) *se
 func (s *ServerPool) GetService(name string) *service {
 	v, ok := s.Services.Load(name)
 	if ok && v != nil {
-		logger.Info("[LB] load from cache:", name)
+		lbLog.Info("[LB] load from cache", "service", name)
 		return v.(*service)
 	} else {
 		if bootstrap.MthApplication != nil && bootstrap.MthApplication.Registry != nil {
@@ -192,10 +210,10 @@ func unmarshal(ctx context.Context, resp *response.Response, respResult any) err
 				if len(str) > 1000 {
 					str = utils.SubStr(str, 0, 1000)
 				}
-				logger.InfoContext(ctx, "[LB] response:", str)
+				lbLog.InfoContext(ctx, "[LB] response", "body", str)
 			})
 		} else {
-			logger.InfoContext(ctx, "[http] response:stream not log")
+			lbLog.InfoContext(ctx, "[http] response:stream not log")
 		}
 	}
 	return nil
@@ -210,11 +228,11 @@ func (s *ServerPool) Run(req *request.Request, respResult any) (*response.Respon
 		loggerCtx = tracer.WithTraceID(loggerCtx)
 	}
 	req.Context = loggerCtx
-	logger.InfoContext(loggerCtx, "[LB] >>>>>>LbOptions", req.LbOptions)
+	lbLog.InfoContext(loggerCtx, "[LB] >>>>>>LbOptions", "options", req.LbOptions)
 	start := time.Now()
 	if len(req.ServiceName) == 0 {
 		defer requestEnd(loggerCtx, req.Url, start)
-		resp, err := s.client.Exec(req)
+		resp, err := s.exec(req, urlHost(req.Url))
 		unmarshal(loggerCtx, resp, respResult)
 		return resp, err
 	}
@@ -223,7 +241,7 @@ func (s *ServerPool) Run(req *request.Request, respResult any) (*response.Respon
 		req.Url = s.GetUrl(req.ServiceName, req.Path)
 		defer requestEnd(loggerCtx, req.Url, start)
 		logger.WarnContext(loggerCtx, "no available service for "+req.ServiceName)
-		resp, err := s.client.Exec(req)
+		resp, err := s.exec(req, urlHost(req.Url))
 		unmarshal(loggerCtx, resp, respResult)
 		return resp, err
 	}
@@ -246,7 +264,7 @@ func (s *ServerPool) Run(req *request.Request, respResult any) (*response.Respon
 	}
 
 	instance := srv.GetNextPeer()
-	logger.InfoContext(loggerCtx, "[LB] get instance:", req.ServiceName, "=>", instance)
+	lbLog.InfoContext(loggerCtx, "[LB] get instance", "service", req.ServiceName, "instance", instance)
 	if instance == nil {
 		return &response.Response{}, errors.New("no available service" + req.ServiceName)
 	}
@@ -259,10 +277,11 @@ func (s *ServerPool) Run(req *request.Request, respResult any) (*response.Respon
 	}
 	req.H2C = srv.H2C
 	defer requestEnd(loggerCtx, req.Url, start)
-	resp, err := s.client.Exec(req)
+	resp, err := s.exec(req, instanceLabel(instance))
 	if err != nil {
 		if s.client.CheckRetry(err, resp.StatusCode) {
 			logger.InfoContext(loggerCtx, "[LB] retry next:", req.ServiceName)
+			clientRetries.WithLabelValues(req.ServiceName).Inc()
 			retrys += 1
 			lbo.Retrys = retrys
 			lbo.CurrentStatuCode = resp.StatusCode
@@ -281,7 +300,8 @@ func (s *ServerPool) Run(req *request.Request, respResult any) (*response.Respon
 }
 
 func requestEnd(ctx context.Context, url string, start time.Time) {
-	worker.AsyncExecute(func() {
-		logger.InfofContext(ctx, "[http] request url method return :%s,elapsed:%dms", url, time.Since(start).Milliseconds())
+	elapsed := time.Since(start).Milliseconds()
+	worker.AsyncExecuteContext(ctx, func(ctx context.Context) {
+		lbLog.InfoContext(ctx, "[LB] requestEnd", "url", url, "elapsedMs", elapsed)
 	})
 }
@@ -0,0 +1,58 @@
+package lb
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/skirrund/gcloud/registry"
+	"github.com/skirrund/gcloud/server/metrics"
+	"github.com/skirrund/gcloud/server/request"
+	"github.com/skirrund/gcloud/server/response"
+)
+
+var (
+	clientHistogram = metrics.Register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "http_client",
+		Name:      "requests_seconds",
+		Help:      "Histogram of outbound request latency (seconds) by service and instance, one observation per attempt.",
+	}, []string{"service", "instance", "method", "code"}))
+	clientRetries = metrics.Register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "http_client",
+		Name:      "retries_total",
+		Help:      "Outbound requests retried on the next instance.",
+	}, []string{"service"}))
+	clientErrors = metrics.Register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "http_client",
+		Name:      "errors_total",
+		Help:      "Outbound requests that failed, including retried attempts.",
+	}, []string{"service", "instance"}))
+)
+
+func instanceLabel(instance *registry.Instance) string {
+	return net.JoinHostPort(instance.Ip, strconv.FormatUint(instance.Port, 10))
+}
+
+func urlHost(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return u.Host
+	}
+	return ""
+}
+
+// exec runs one attempt and records it under service and instance
+func (s *ServerPool) exec(req *request.Request, instance string) (*response.Response, error) {
+	start := time.Now()
+	resp, err := s.do(req, instance)
+	code := "0"
+	if resp != nil && resp.StatusCode > 0 {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	clientHistogram.WithLabelValues(req.ServiceName, instance, req.Method, code).Observe(time.Since(start).Seconds())
+	if err != nil {
+		clientErrors.WithLabelValues(req.ServiceName, instance).Inc()
+	}
+	return resp, err
+}
@@ -0,0 +1,178 @@
+// Package metrics exposes the worker, redis, database and local cache pools and the log sinks to prometheus.
+// Pools register themselves under a name, which becomes the "pool" label.
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/redis/go-redis/v9"
+	"github.com/skirrund/gcloud/logger"
+)
+
+const Namespace = "gcloud"
+
+// Register registers c with the default registerer. When an equal collector is already
+// registered, e.g. by an earlier server in the same process, the existing one is returned.
+func Register[T prometheus.Collector](c T) T {
+	err := prometheus.Register(c)
+	if err == nil {
+		return c
+	}
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+	logger.Warn("[metrics] register error:", err.Error())
+	return c
+}
+
+type value struct {
+	desc *prometheus.Desc
+	typ  prometheus.ValueType
+	get  func() float64
+}
+
+// poolCollector reads the values of one pool on every scrape
+type poolCollector struct {
+	values []value
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, v := range c.values {
+		ch <- v.desc
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, v := range c.values {
+		ch <- prometheus.MustNewConstMetric(v.desc, v.typ, v.get())
+	}
+}
+
+func (c *poolCollector) add(subsystem, name, help string, pool string, typ prometheus.ValueType, get func() float64) {
+	desc := prometheus.NewDesc(prometheus.BuildFQName(Namespace, subsystem, name), help, nil, prometheus.Labels{"pool": pool})
+	c.values = append(c.values, value{desc: desc, typ: typ, get: get})
+}
+
+type WorkerPool interface {
+	Running() int
+	Cap() int
+	Waiting() int
+}
+
+// RegisterWorkerPool exposes the running, capacity and waiting gauges of a goroutine pool,
+// and the rejected and panic counters when the pool has Rejected() or Panics()
+func RegisterWorkerPool(name string, p WorkerPool) {
+	c := &poolCollector{}
+	c.add("worker", "running", "Goroutines currently running tasks.", name, prometheus.GaugeValue, func() float64 { return float64(p.Running()) })
+	c.add("worker", "capacity", "Capacity of the pool.", name, prometheus.GaugeValue, func() float64 { return float64(p.Cap()) })
+	c.add("worker", "waiting", "Tasks waiting for a free goroutine.", name, prometheus.GaugeValue, func() float64 { return float64(p.Waiting()) })
+	if r, ok := p.(interface{ Rejected() uint64 }); ok {
+		c.add("worker", "rejected_total", "Tasks that found the queue full.", name, prometheus.CounterValue, func() float64 { return float64(r.Rejected()) })
+	}
+	if r, ok := p.(interface{ Panics() uint64 }); ok {
+		c.add("worker", "panics_total", "Tasks that panicked.", name, prometheus.CounterValue, func() float64 { return float64(r.Panics()) })
+	}
+	Register(c)
+}
+
+type RedisPool interface {
+	PoolStats() *redis.PoolStats
+}
+
+// RegisterRedisPool exposes the connection pool stats of a redis client
+func RegisterRedisPool(name string, p RedisPool) {
+	c := &poolCollector{}
+	stat := func(f func(s *redis.PoolStats) uint32) func() float64 {
+		return func() float64 { return float64(f(p.PoolStats())) }
+	}
+	c.add("redis_pool", "hits_total", "Times a free connection was found in the pool.", name, prometheus.CounterValue, stat(func(s *redis.PoolStats) uint32 { return s.Hits }))
+	c.add("redis_pool", "misses_total", "Times a free connection was not found in the pool.", name, prometheus.CounterValue, stat(func(s *redis.PoolStats) uint32 { return s.Misses }))
+	c.add("redis_pool", "timeouts_total", "Times waiting for a connection timed out.", name, prometheus.CounterValue, stat(func(s *redis.PoolStats) uint32 { return s.Timeouts }))
+	c.add("redis_pool", "total_conns", "Connections in the pool.", name, prometheus.GaugeValue, stat(func(s *redis.PoolStats) uint32 { return s.TotalConns }))
+	c.add("redis_pool", "idle_conns", "Idle connections in the pool.", name, prometheus.GaugeValue, stat(func(s *redis.PoolStats) uint32 { return s.IdleConns }))
+	c.add("redis_pool", "stale_conns", "Stale connections removed from the pool.", name, prometheus.CounterValue, stat(func(s *redis.PoolStats) uint32 { return s.StaleConns }))
+	Register(c)
+}
+
+// RegisterDB exposes sql.DBStats as the go_sql_* metrics labelled with db_name
+func RegisterDB(name string, db *sql.DB) {
+	Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// CacheStats is a snapshot of the counters of a local cache
+type CacheStats interface {
+	Hits() int64
+	Misses() int64
+	RejectedSets() int64
+	EvictedCount() int64
+}
+
+// RegisterCache exposes the hit, miss and eviction counters and the size of a local cache
+func RegisterCache(name string, size func() int, stats func() CacheStats) {
+	c := &poolCollector{}
+	c.add("cache", "hits_total", "Cache hits.", name, prometheus.CounterValue, func() float64 { return float64(stats().Hits()) })
+	c.add("cache", "misses_total", "Cache misses.", name, prometheus.CounterValue, func() float64 { return float64(stats().Misses()) })
+	c.add("cache", "rejected_sets_total", "Sets rejected by the admission policy.", name, prometheus.CounterValue, func() float64 { return float64(stats().RejectedSets()) })
+	c.add("cache", "evictions_total", "Evicted entries.", name, prometheus.CounterValue, func() float64 { return float64(stats().EvictedCount()) })
+	c.add("cache", "size", "Entries in the cache.", name, prometheus.GaugeValue, func() float64 { return float64(size()) })
+	Register(c)
+}
+
+var (
+	sinkQueuedDesc  = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "log_sink", "queued"), "Log records waiting to be shipped.", []string{"sink"}, nil)
+	sinkShippedDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "log_sink", "shipped_total"), "Log records shipped.", []string{"sink"}, nil)
+	sinkDroppedDesc = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "log_sink", "dropped_total"), "Log records dropped because the queue was full.", []string{"sink"}, nil)
+	sinkFailedDesc  = prometheus.NewDesc(prometheus.BuildFQName(Namespace, "log_sink", "failed_total"), "Log records of batches the sink failed to ship.", []string{"sink"}, nil)
+)
+
+// logSinkCollector reads the counters of the sinks added at the time of the scrape
+type logSinkCollector struct{}
+
+func (logSinkCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sinkQueuedDesc
+	ch <- sinkShippedDesc
+	ch <- sinkDroppedDesc
+	ch <- sinkFailedDesc
+}
+
+func (logSinkCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range logger.Sinks() {
+		ch <- prometheus.MustNewConstMetric(sinkQueuedDesc, prometheus.GaugeValue, float64(s.Queued), s.Name)
+		ch <- prometheus.MustNewConstMetric(sinkShippedDesc, prometheus.CounterValue, float64(s.Shipped), s.Name)
+		ch <- prometheus.MustNewConstMetric(sinkDroppedDesc, prometheus.CounterValue, float64(s.Dropped), s.Name)
+		ch <- prometheus.MustNewConstMetric(sinkFailedDesc, prometheus.CounterValue, float64(s.Failed), s.Name)
+	}
+}
+
+// RegisterLogSinks exposes the queued, shipped, dropped and failed counters of the log sinks
+func RegisterLogSinks() {
+	Register(logSinkCollector{})
+}
+
+// RegisterLogThrottling exposes the log entries dropped by sampling and by the rate limits
+func RegisterLogThrottling() {
+	Register(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "log",
+		Name:      "sampled_total",
+		Help:      "Log entries dropped by sampling.",
+	}, func() float64 {
+		sampled, _ := logger.Throttled()
+		return float64(sampled)
+	}))
+	Register(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "log",
+		Name:      "rate_limited_total",
+		Help:      "Log entries dropped by the rate limits.",
+	}, func() float64 {
+		_, limited := logger.Throttled()
+		return float64(limited)
+	}))
+}
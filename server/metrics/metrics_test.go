@@ -0,0 +1,19 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRegisterReturnsExisting(t *testing.T) {
+	opts := prometheus.CounterOpts{Namespace: Namespace, Name: "test_total", Help: "test"}
+	first := Register(prometheus.NewCounter(opts))
+	t.Cleanup(func() { prometheus.Unregister(first) })
+	second := Register(prometheus.NewCounter(opts))
+	first.Inc()
+	if testutil.ToFloat64(second) != 1 {
+		t.Error("second registration should return the first collector")
+	}
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is the number of Allow calls between scans for idle buckets
+const sweepEvery = 4096
+
+type bucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	// full is when the bucket is refilled and can be forgotten
+	full time.Time
+}
+
+// LocalLimiter is an in-process token bucket per key
+type LocalLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+	now     func() time.Time
+}
+
+func NewLocal() *LocalLimiter {
+	return &LocalLimiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (l *LocalLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if !limit.valid() {
+		return Result{Allowed: true}, nil
+	}
+	now := l.now()
+	burst := float64(limit.burst())
+	l.mu.Lock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	l.calls++
+	if l.calls%sweepEvery == 0 {
+		l.sweep(now)
+	}
+	l.mu.Unlock()
+
+	interval := limit.interval()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(interval)
+		if b.tokens > burst {
+			b.tokens = burst
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		retry := time.Duration((1 - b.tokens) * float64(interval))
+		return Result{RetryAfter: retry}, nil
+	}
+	b.tokens--
+	b.full = now.Add(time.Duration((burst - b.tokens) * float64(interval)))
+	return Result{Allowed: true, Remaining: int(b.tokens)}, nil
+}
+
+// sweep drops buckets that are back to full, they behave exactly like new ones
+func (l *LocalLimiter) sweep(now time.Time) {
+	for k, b := range l.buckets {
+		b.mu.Lock()
+		idle := !b.full.After(now)
+		b.mu.Unlock()
+		if idle {
+			delete(l.buckets, k)
+		}
+	}
+}
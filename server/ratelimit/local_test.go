@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLocalLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := NewLocal()
+	l.now = func() time.Time { return now }
+	limit := Limit{Events: 2, Period: time.Second, Burst: 3}
+	for i := 0; i < 3; i++ {
+		if res, _ := l.Allow(context.Background(), "k", limit); !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("call %d: %+v", i, res)
+		}
+	}
+	res, _ := l.Allow(context.Background(), "k", limit)
+	if res.Allowed || res.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("expected denial with 500ms retry, got %+v", res)
+	}
+	if res, _ := l.Allow(context.Background(), "other", limit); !res.Allowed {
+		t.Fatal("keys must not share buckets")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if res, _ := l.Allow(context.Background(), "k", limit); !res.Allowed {
+		t.Fatalf("expected refill after retry, got %+v", res)
+	}
+}
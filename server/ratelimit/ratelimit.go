@@ -0,0 +1,51 @@
+// Package ratelimit provides token bucket limiters backed by process memory or Redis.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit allows Events per Period with bursts of up to Burst events
+type Limit struct {
+	Events int
+	Period time.Duration
+	// Burst defaults to Events
+	Burst int
+}
+
+func PerSecond(events int) Limit {
+	return Limit{Events: events, Period: time.Second}
+}
+
+func PerMinute(events int) Limit {
+	return Limit{Events: events, Period: time.Minute}
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Events
+}
+
+// interval is the time it takes to earn back one event
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Events)
+}
+
+func (l Limit) valid() bool {
+	return l.Events > 0 && l.Period > 0
+}
+
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long to wait before the next event is allowed, zero when Allowed
+	RetryAfter time.Duration
+}
+
+type Limiter interface {
+	// Allow takes one event for key, an invalid limit always allows
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
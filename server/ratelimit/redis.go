@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const DefaultRedisPrefix = "gcloud:ratelimit:"
+
+// gcra keeps the theoretical arrival time of the next event per key,
+// times come from the Redis server so instances need no clock agreement.
+var gcra = redis.NewScript(`
+redis.replicate_commands()
+local key = KEYS[1]
+local burst = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+local tat = tonumber(redis.call("GET", key))
+if not tat or tat < now then
+  tat = now
+end
+local new_tat = tat + interval
+local diff = now - (new_tat - burst * interval)
+if diff < 0 then
+  return {0, 0, tostring(-diff)}
+end
+local ttl = math.ceil(new_tat - now)
+if ttl > 0 then
+  redis.call("SET", key, tostring(new_tat), "EX", ttl)
+end
+return {1, math.floor(diff / interval), "0"}
+`)
+
+// RedisLimiter runs GCRA in a Lua script, so every instance shares the same buckets
+type RedisLimiter struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedis(client redis.UniversalClient, prefix string) *RedisLimiter {
+	if len(prefix) == 0 {
+		prefix = DefaultRedisPrefix
+	}
+	return &RedisLimiter{client: client, prefix: prefix}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if !limit.valid() {
+		return Result{Allowed: true}, nil
+	}
+	interval := limit.interval().Seconds()
+	v, err := gcra.Run(ctx, l.client, []string{l.prefix + key}, limit.burst(), interval).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	allowed, _ := v[0].(int64)
+	remaining, _ := v[1].(int64)
+	retry, _ := v[2].(string)
+	seconds, _ := strconv.ParseFloat(retry, 64)
+	return Result{
+		Allowed:    allowed == 1,
+		Remaining:  int(remaining),
+		RetryAfter: time.Duration(seconds * float64(time.Second)),
+	}, nil
+}
@@ -1,11 +1,13 @@
 package server
 
 import (
+	"context"
 	"errors"
-	"sync"
+	"slices"
 	"time"
 
 	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/server/eventbus"
 )
 
 type Server interface {
@@ -14,15 +16,29 @@ type Server interface {
 	GetServeServer() any
 }
 
+// LifecycleServer is implemented by servers that report when the listener
+// accepts connections and run hooks before in-flight requests are drained.
+type LifecycleServer interface {
+	Server
+	OnStarted(f ...func())
+	OnShutdown(f ...func())
+}
+
 type Options struct {
 	ServerName           string
 	Address              string
 	Concurrency          int
+	ReadTimeout          time.Duration
+	ReadHeaderTimeout    time.Duration
+	WriteTimeout         time.Duration
 	IdleTimeout          time.Duration
+	ShutdownTimeout      time.Duration
+	MaxHeaderBytes       int
 	MaxRequestBodySize   int
 	H2C                  bool
 	H2                   bool
 	MaxConcurrentStreams uint32
+	TLS                  TLSOptions
 	//Container  Server
 	//	Registry   registry.IRegistry
 	//	Config     config.IConfig
@@ -31,6 +47,23 @@ type Options struct {
 	//IdWorker   *common.Worker
 }
 
+// TLSOptions enables https when both files are set,
+// the certificate is reloaded from the files on CertRenewEvent
+type TLSOptions struct {
+	CertFile string
+	KeyFile  string
+}
+
+func (o TLSOptions) Enabled() bool {
+	return len(o.CertFile) > 0 && len(o.KeyFile) > 0
+}
+
+// CertRenewInfo may be emitted with CertRenewEvent to switch to new certificate files
+type CertRenewInfo struct {
+	CertFile string
+	KeyFile  string
+}
+
 type EventName string
 
 const (
@@ -46,11 +79,8 @@ const (
 
 type EventHook func(eventType EventName, eventInfo any) error
 
-var eventHooks = struct {
-	sync.Mutex
-	EventHook map[EventName][]EventHook
-}{EventHook: make(map[EventName][]EventHook)}
-
+// EventHook registrations are adapters over the default typed event bus,
+// see OnEvent for typed subscriptions.
 func RegisterEventHookFirst(name EventName, hook ...EventHook) error {
 	return doRegisterEventHook(name, true, hook...)
 }
@@ -61,20 +91,18 @@ func doRegisterEventHook(name EventName, isFirst bool, hook ...EventHook) error
 		return errors.New("[server] event hook must have a name")
 	}
 	logger.Info("[server] RegisterEventHook:"+name, hook)
-	v, ok := eventHooks.EventHook[name]
-	var list []EventHook
-	list = append(list, hook...)
-	if ok {
-		if isFirst {
-			list = append(list, v...)
-		} else {
-			list = append(v, list...)
-		}
+	var opts []eventbus.SubscribeOption
+	if isFirst {
+		opts = append(opts, eventbus.First())
+		// keep the given order when each one is placed first
+		hook = slices.Clone(hook)
+		slices.Reverse(hook)
+	}
+	for _, h := range hook {
+		eventbus.Subscribe(eventbus.Default(), string(name), func(ctx context.Context, info any) error {
+			return h(name, info)
+		}, opts...)
 	}
-	v = list
-	eventHooks.Lock()
-	defer eventHooks.Unlock()
-	eventHooks.EventHook[name] = v
 	return nil
 }
 
@@ -82,21 +110,21 @@ func RegisterEventHook(name EventName, hook ...EventHook) error {
 	return doRegisterEventHook(name, false, hook...)
 }
 
-// EmitEvent executes the different hooks passing the EventType as an
-// argument. This is a blocking function. Hook developers should
-// use 'go' keyword if they don't want to block Caddy.
+// OnEvent subscribes a typed handler, it only receives events whose info is a T
+func OnEvent[T any](name EventName, h eventbus.Handler[T], opts ...eventbus.SubscribeOption) *eventbus.Subscription {
+	return eventbus.Subscribe(eventbus.Default(), string(name), h, opts...)
+}
+
+// EmitEvent queues the event for the registered hooks and returns immediately.
+// Events of the same name are delivered in the order they were emitted.
 func EmitEvent(event EventName, info any) {
-	funcs, ok := eventHooks.EventHook[event]
-	if ok {
+	if eventbus.Default().HasSubscribers(string(event)) {
 		logger.Info("[server] EmitEvent exec ", event)
-		go func() {
-			for i := range funcs {
-				f := funcs[i]
-				err := f(event, info)
-				if err != nil {
-					logger.Infof("[server] error on '%s' hook: %v", event, err)
-				}
-			}
-		}()
+		eventbus.PublishAsync(context.Background(), eventbus.Default(), string(event), info)
 	}
 }
+
+// EmitEventSync runs the hooks in the caller goroutine and returns their joined errors
+func EmitEventSync(ctx context.Context, event EventName, info any) error {
+	return eventbus.Publish(ctx, eventbus.Default(), string(event), info)
+}
@@ -0,0 +1,44 @@
+package tracer
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName names the tracer the spans of this module are started with
+const InstrumentationName = "github.com/skirrund/gcloud"
+
+// Tracer returns the tracer of the global TracerProvider, spans are no-ops until plugins/otel is initialized
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Start starts a span with Tracer. A new root takes the legacy trace id of ctx as its trace id
+// when the provider uses the id generator of plugins/otel.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// Extract reads the remote span context, e.g. the W3C traceparent header, with the global propagator
+func Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// Inject writes the span context of ctx with the global propagator
+func Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+}
+
+// LegacyTraceID returns the legacy trace id of ctx as an OTel trace id.
+// ok is false when there is none or it is not 32 hex digits, e.g. ids sent by old clients.
+func LegacyTraceID(ctx context.Context) (trace.TraceID, bool) {
+	id, _ := ctx.Value(GetCtxKey()).(string)
+	if len(id) == 0 {
+		return trace.TraceID{}, false
+	}
+	tid, err := trace.TraceIDFromHex(id)
+	return tid, err == nil
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type CtxTraceId struct {
@@ -45,10 +46,18 @@ func WithTraceID(ctx context.Context) context.Context {
 	return WithContext(ctx, "")
 }
 
+// GetTraceID returns the legacy trace id of ctx, or the trace id of its span when it has none
 func GetTraceID(ctx context.Context) (traceId any) {
-	return ctx.Value(GetCtxKey())
+	if id := ctx.Value(GetCtxKey()); id != nil {
+		return id
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID().String()
+	}
+	return nil
 }
 
+// GenerateId returns 32 hex digits, a valid OTel trace id
 func GenerateId() string {
 	uidG, _ := uuid.NewV7()
 	return strings.ReplaceAll(uidG.String(), "-", "")
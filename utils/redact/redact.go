@@ -0,0 +1,382 @@
+// Package redact masks sensitive values (passwords, ID numbers, phones, bank cards)
+// before request/response bodies and log messages are written out.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/skirrund/gcloud/utils"
+)
+
+const (
+	DetectorIdCard   = "idcard"
+	DetectorPhone    = "phone"
+	DetectorBankCard = "bankcard"
+)
+
+var (
+	DefaultFields    = []string{"password", "passwd", "pwd", "secret", "token", "idNo", "idCard", "phone", "mobile", "bankCard", "cardNo"}
+	DefaultDetectors = []string{DetectorIdCard, DetectorPhone, DetectorBankCard}
+)
+
+var digitsReg = regexp.MustCompile(`[0-9]+[Xx]?`)
+
+type Config struct {
+	// Fields are key names masked at any depth of JSON and form bodies, case-insensitive
+	Fields []string
+	// Paths are dotted key paths from the JSON root, e.g. "user.idNo" or "items.*.phone".
+	// "*" matches any single key and array elements are transparent.
+	Paths []string
+	// Detectors scan free text for DetectorIdCard, DetectorPhone and DetectorBankCard
+	Detectors []string
+	// Patterns are extra regular expressions whose matches are masked, keyed by name
+	Patterns map[string]string
+}
+
+// DefaultConfig masks DefaultFields and runs all detectors
+func DefaultConfig() Config {
+	return Config{Fields: DefaultFields, Detectors: DefaultDetectors}
+}
+
+type Redactor struct {
+	fields   map[string]struct{}
+	paths    [][]string
+	fieldReg *regexp.Regexp
+	idCard   bool
+	phone    bool
+	bankCard bool
+	patterns []*regexp.Regexp
+}
+
+func New(cfg Config) (*Redactor, error) {
+	r := &Redactor{fields: make(map[string]struct{}, len(cfg.Fields))}
+	quoted := make([]string, 0, len(cfg.Fields))
+	for _, f := range cfg.Fields {
+		f = strings.TrimSpace(f)
+		if len(f) == 0 {
+			continue
+		}
+		r.fields[strings.ToLower(f)] = struct{}{}
+		quoted = append(quoted, regexp.QuoteMeta(f))
+	}
+	if len(quoted) > 0 {
+		r.fieldReg = regexp.MustCompile(`(?i)(\b(?:` + strings.Join(quoted, "|") + `)"?\s*[:=]\s*"?)([^"&,;\s}\]]+)`)
+	}
+	for _, p := range cfg.Paths {
+		p = strings.TrimPrefix(strings.TrimSpace(p), "$.")
+		if len(p) > 0 {
+			r.paths = append(r.paths, strings.Split(strings.ToLower(p), "."))
+		}
+	}
+	for _, d := range cfg.Detectors {
+		switch strings.ToLower(d) {
+		case DetectorIdCard:
+			r.idCard = true
+		case DetectorPhone:
+			r.phone = true
+		case DetectorBankCard:
+			r.bankCard = true
+		}
+	}
+	for _, p := range cfg.Patterns {
+		reg, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		r.patterns = append(r.patterns, reg)
+	}
+	return r, nil
+}
+
+// defaultRedactor is nil until SetDefault, redaction is off unless it is configured
+var defaultRedactor atomic.Pointer[Redactor]
+
+// Default returns the redactor used by the package level functions, nil when disabled
+func Default() *Redactor {
+	return defaultRedactor.Load()
+}
+
+// SetDefault replaces the default redactor, nil disables redaction
+func SetDefault(r *Redactor) {
+	defaultRedactor.Store(r)
+}
+
+func String(s string) string {
+	return Default().String(s)
+}
+
+func JSON(b []byte) []byte {
+	return Default().JSON(b)
+}
+
+func Form(s string) string {
+	return Default().Form(s)
+}
+
+func Body(contentType string, body string) string {
+	return Default().Body(contentType, body)
+}
+
+func Field(key string, value string) string {
+	return Default().Field(key, value)
+}
+
+// Mask hides the middle of v, values of 6 runes or fewer are hidden entirely
+func Mask(v string) string {
+	if l := len([]rune(v)); l <= 6 {
+		return strings.Repeat("*", l)
+	}
+	return utils.Mask(v, 2, 2)
+}
+
+// Body redacts a body according to its content type
+func (r *Redactor) Body(contentType string, body string) string {
+	if r == nil || len(body) == 0 {
+		return body
+	}
+	mt, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.Contains(mt, "json"):
+		return string(r.JSON([]byte(body)))
+	case mt == "application/x-www-form-urlencoded":
+		return r.Form(body)
+	}
+	return r.String(body)
+}
+
+// String masks key=value and "key":"value" pairs of the configured fields,
+// the configured patterns and whatever the detectors recognise in free text
+func (r *Redactor) String(s string) string {
+	if r == nil || len(s) == 0 {
+		return s
+	}
+	if r.fieldReg != nil {
+		s = r.fieldReg.ReplaceAllStringFunc(s, func(m string) string {
+			sub := r.fieldReg.FindStringSubmatch(m)
+			return sub[1] + Mask(sub[2])
+		})
+	}
+	for _, p := range r.patterns {
+		s = p.ReplaceAllStringFunc(s, Mask)
+	}
+	return r.detect(s)
+}
+
+func (r *Redactor) detect(s string) string {
+	if !r.idCard && !r.phone && !r.bankCard {
+		return s
+	}
+	return digitsReg.ReplaceAllStringFunc(s, func(m string) string {
+		switch {
+		case r.idCard && (len(m) == 15 || len(m) == 18) && utils.IsIdNoCorrect(strings.ToUpper(m)):
+			return utils.Mask(m, 4, 4)
+		case r.phone && isPhone(m):
+			return utils.Mask(m, 3, 4)
+		case r.bankCard && len(m) >= 16 && len(m) <= 19 && luhn(m):
+			return utils.Mask(m, 6, 4)
+		}
+		return m
+	})
+}
+
+func isPhone(s string) bool {
+	return len(s) == 11 && s[0] == '1' && s[1] >= '3' && s[1] <= '9'
+}
+
+func luhn(s string) bool {
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		d := int(s[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func (r *Redactor) matchKey(path []string, key string) bool {
+	k := strings.ToLower(key)
+	if _, ok := r.fields[k]; ok {
+		return true
+	}
+	for _, p := range r.paths {
+		if len(p) != len(path)+1 || (p[len(path)] != "*" && p[len(path)] != k) {
+			continue
+		}
+		matched := true
+		for i, seg := range path {
+			if p[i] != "*" && p[i] != strings.ToLower(seg) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Field masks value when key is a configured field, otherwise it runs String on it
+func (r *Redactor) Field(key string, value string) string {
+	if r == nil {
+		return value
+	}
+	if r.matchKey(nil, key) {
+		return Mask(value)
+	}
+	return r.String(value)
+}
+
+// Form redacts an application/x-www-form-urlencoded body keeping the field order
+func (r *Redactor) Form(s string) string {
+	if r == nil || len(s) == 0 {
+		return s
+	}
+	pairs := strings.Split(s, "&")
+	for i, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		key, err := url.QueryUnescape(k)
+		if err != nil {
+			key = k
+		}
+		val, err := url.QueryUnescape(v)
+		if err != nil {
+			val = v
+		}
+		if masked := r.Field(key, val); masked != val {
+			pairs[i] = k + "=" + url.QueryEscape(masked)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+type frame struct {
+	object    bool
+	expectKey bool
+	masked    bool
+	pushed    bool
+	key       string
+	n         int
+}
+
+// JSON redacts a JSON document keeping its key order, bodies that fail to parse
+// (e.g. truncated ones) fall back to String
+func (r *Redactor) JSON(b []byte) []byte {
+	if r == nil || len(b) == 0 {
+		return b
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	out := &bytes.Buffer{}
+	out.Grow(len(b))
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	writeString := func(s string) {
+		enc.Encode(s)
+		out.Truncate(out.Len() - 1)
+	}
+	var stack []*frame
+	var path []string
+	done := func() {
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			top.n++
+			top.expectKey = top.object
+		}
+	}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return []byte(r.String(string(b)))
+		}
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			out.WriteByte(byte(d))
+			stack = stack[:len(stack)-1]
+			if top.pushed {
+				path = path[:len(path)-1]
+			}
+			done()
+			continue
+		}
+		masked := false
+		if top != nil {
+			if top.object && top.expectKey {
+				if top.n > 0 {
+					out.WriteByte(',')
+				}
+				top.key, _ = tok.(string)
+				writeString(top.key)
+				out.WriteByte(':')
+				top.expectKey = false
+				continue
+			}
+			if !top.object && top.n > 0 {
+				out.WriteByte(',')
+			}
+			masked = top.masked || (top.object && r.matchKey(path, top.key))
+		}
+		switch v := tok.(type) {
+		case json.Delim:
+			out.WriteByte(byte(v))
+			f := &frame{object: v == '{', expectKey: v == '{', masked: masked}
+			if top != nil && top.object {
+				path = append(path, top.key)
+				f.pushed = true
+			}
+			stack = append(stack, f)
+			continue
+		case string:
+			if masked {
+				writeString(Mask(v))
+			} else {
+				writeString(r.String(v))
+			}
+		case json.Number:
+			s := v.String()
+			if masked {
+				writeString(Mask(s))
+			} else if d := r.detect(s); d != s {
+				writeString(d)
+			} else {
+				out.WriteString(s)
+			}
+		case bool:
+			if v {
+				out.WriteString("true")
+			} else {
+				out.WriteString("false")
+			}
+		case nil:
+			out.WriteString("null")
+		}
+		done()
+	}
+	return out.Bytes()
+}
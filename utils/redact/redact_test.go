@@ -0,0 +1,40 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	r, err := New(Config{
+		Fields:    []string{"password"},
+		Paths:     []string{"user.name", "items.*.secret"},
+		Detectors: DefaultDetectors,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := `{"password":"abc123456","user":{"name":"zhangsan001","age":18},"items":[{"a":{"secret":"x"}}],"remark":"tel 13800138000","name":"keep"}`
+	want := `{"password":"ab*****56","user":{"name":"zh*******01","age":18},"items":[{"a":{"secret":"*"}}],"remark":"tel 138****8000","name":"keep"}`
+	if got := string(r.JSON([]byte(in))); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	// truncated bodies fall back to text redaction
+	if got := string(r.JSON([]byte(`{"password":"abc123456","x`))); strings.Contains(got, "abc123456") {
+		t.Errorf("password leaked: %s", got)
+	}
+}
+
+func TestFormAndString(t *testing.T) {
+	r, _ := New(DefaultConfig())
+	if got := r.Form("phone=13800138000&name=a%20b"); got != "phone=13%2A%2A%2A%2A%2A%2A%2A00&name=a%20b" {
+		t.Errorf("form: %s", got)
+	}
+	s := r.String("id 11010519491231002X card 6222020200112233445")
+	if strings.Contains(s, "11010519491231002X") {
+		t.Errorf("id number leaked: %s", s)
+	}
+	if got := r.String("order 12345678901234"); got != "order 12345678901234" {
+		t.Errorf("unexpected mask: %s", got)
+	}
+}
@@ -0,0 +1,313 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+	"github.com/skirrund/gcloud/logger"
+	"github.com/skirrund/gcloud/server/metrics"
+	"github.com/skirrund/gcloud/tracer"
+)
+
+// RejectPolicy decides what Submit does when the queue of a pool is full
+type RejectPolicy int
+
+const (
+	// Block waits for room in the queue until the context of the task ends
+	Block RejectPolicy = iota
+	// Drop returns ErrRejected
+	Drop
+	// CallerRuns runs the task on the goroutine calling Submit
+	CallerRuns
+)
+
+const DefaultQueueSize = 1024
+
+var (
+	ErrRejected = errors.New("worker: queue is full")
+	ErrClosed   = errors.New("worker: pool is closed")
+)
+
+// ParsePolicy parses block, drop or caller-runs
+func ParsePolicy(s string) (RejectPolicy, error) {
+	switch strings.ToLower(s) {
+	case "", "block":
+		return Block, nil
+	case "drop":
+		return Drop, nil
+	case "caller-runs", "callerruns":
+		return CallerRuns, nil
+	}
+	return Block, fmt.Errorf("worker: unknown reject policy %s", s)
+}
+
+type Options struct {
+	// Size is the most tasks running at once, DefaultLimit when zero
+	Size int
+	// QueueSize is the most tasks waiting for a goroutine, DefaultQueueSize when zero
+	QueueSize int
+	// Policy applies when the queue is full
+	Policy RejectPolicy
+	// ExpiryDuration after which idle goroutines exit, 10s when zero
+	ExpiryDuration time.Duration
+}
+
+type task struct {
+	ctx context.Context
+	f   func(ctx context.Context)
+}
+
+// Pool runs tasks on at most Size goroutines, tasks wait in a bounded queue for a free one
+type Pool struct {
+	name  string
+	opts  Options
+	ants  *ants.Pool
+	queue chan task
+	done  chan struct{}
+
+	mu      sync.RWMutex
+	closed  bool
+	pending sync.WaitGroup
+
+	workers  atomic.Int32
+	running  atomic.Int32
+	rejected atomic.Uint64
+	panics   atomic.Uint64
+}
+
+var (
+	poolsMu sync.Mutex
+	pools   = make(map[string]*Pool)
+)
+
+// NewPool creates a pool. A named pool is exposed to prometheus, returned by Get and drained by DrainAll.
+func NewPool(name string, opts Options) (*Pool, error) {
+	if opts.Size <= 0 {
+		opts.Size = DefaultLimit
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	if opts.ExpiryDuration <= 0 {
+		opts.ExpiryDuration = 10 * time.Second
+	}
+	ap, err := ants.NewPool(opts.Size, ants.WithExpiryDuration(opts.ExpiryDuration), ants.WithNonblocking(true))
+	if err != nil {
+		return nil, err
+	}
+	p := &Pool{
+		name:  name,
+		opts:  opts,
+		ants:  ap,
+		queue: make(chan task, opts.QueueSize),
+		done:  make(chan struct{}),
+	}
+	if len(name) == 0 {
+		return p, nil
+	}
+	poolsMu.Lock()
+	if _, ok := pools[name]; ok {
+		poolsMu.Unlock()
+		ap.Release()
+		return nil, fmt.Errorf("worker: pool %s already exists", name)
+	}
+	pools[name] = p
+	poolsMu.Unlock()
+	metrics.RegisterWorkerPool(name, p)
+	return p, nil
+}
+
+// Get returns the pool created under name
+func Get(name string) (*Pool, bool) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+	p, ok := pools[name]
+	return p, ok
+}
+
+// DrainAll drains every named pool, it is called on shutdown
+func DrainAll(ctx context.Context) error {
+	poolsMu.Lock()
+	all := make([]*Pool, 0, len(pools))
+	for _, p := range pools {
+		all = append(all, p)
+	}
+	poolsMu.Unlock()
+	errs := make([]error, len(all))
+	var wg sync.WaitGroup
+	for i, p := range all {
+		wg.Go(func() {
+			errs[i] = p.Drain(ctx)
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// Submit queues f. f gets ctx without its cancellation and with a trace id, a new one when ctx has none.
+// Pass the request context rather than a *gin.Context, which is reused once the request ends.
+func (p *Pool) Submit(ctx context.Context, f func(ctx context.Context)) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return ErrClosed
+	}
+	p.pending.Add(1)
+	p.mu.RUnlock()
+	t := task{ctx: tracer.WithTraceID(context.WithoutCancel(ctx)), f: f}
+	select {
+	case p.queue <- t:
+		p.spawn()
+		return nil
+	default:
+	}
+	switch p.opts.Policy {
+	case Drop:
+		p.rejected.Add(1)
+		p.pending.Done()
+		return ErrRejected
+	case CallerRuns:
+		p.rejected.Add(1)
+		p.run(t)
+		return nil
+	}
+	select {
+	case p.queue <- t:
+		p.spawn()
+		return nil
+	case <-ctx.Done():
+		p.rejected.Add(1)
+		p.pending.Done()
+		return ctx.Err()
+	case <-p.done:
+		p.pending.Done()
+		return ErrClosed
+	}
+}
+
+// Go is Submit for functions without a context
+func (p *Pool) Go(f func()) error {
+	return p.Submit(context.Background(), func(context.Context) { f() })
+}
+
+// spawn starts a goroutine taking tasks from the queue unless Size of them already do
+func (p *Pool) spawn() {
+	for {
+		n := p.workers.Load()
+		if int(n) >= p.opts.Size {
+			return
+		}
+		if p.workers.CompareAndSwap(n, n+1) {
+			break
+		}
+	}
+	if err := p.ants.Submit(p.drain); err != nil {
+		// ants may still count a goroutine that has just left drain
+		go p.drain()
+	}
+}
+
+func (p *Pool) drain() {
+	for {
+		select {
+		case t := <-p.queue:
+			p.run(t)
+			continue
+		default:
+		}
+		p.workers.Add(-1)
+		// a task queued while leaving found the pool full and started no goroutine
+		if len(p.queue) == 0 {
+			return
+		}
+		n := p.workers.Load()
+		if int(n) >= p.opts.Size || !p.workers.CompareAndSwap(n, n+1) {
+			return
+		}
+	}
+}
+
+func (p *Pool) run(t task) {
+	defer p.pending.Done()
+	p.running.Add(1)
+	defer p.running.Add(-1)
+	defer func() {
+		if err := recover(); err != nil {
+			p.panics.Add(1)
+			logger.ErrorContext(t.ctx, "[worker] ", p.name, " task panic:", err, "\n", string(debug.Stack()))
+		}
+	}()
+	t.f(t.ctx)
+}
+
+// Drain stops accepting tasks and waits until the queued and running ones are done or ctx ends
+func (p *Pool) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.done)
+	}
+	p.mu.Unlock()
+	finished := make(chan struct{})
+	go func() {
+		p.pending.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		p.ants.Release()
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("worker: drain pool %s: %w, %d running, %d queued", p.name, ctx.Err(), p.Running(), p.Waiting())
+	}
+}
+
+// Release stops accepting tasks without waiting for them
+func (p *Pool) Release() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.done)
+	}
+	p.mu.Unlock()
+	p.ants.Release()
+}
+
+// Running is the number of tasks running
+func (p *Pool) Running() int {
+	return int(p.running.Load())
+}
+
+func (p *Pool) Cap() int {
+	return p.opts.Size
+}
+
+// Waiting is the number of queued tasks
+func (p *Pool) Waiting() int {
+	return len(p.queue)
+}
+
+// Rejected counts the tasks that found the queue full and were dropped, run by the caller
+// or gave up waiting
+func (p *Pool) Rejected() uint64 {
+	return p.rejected.Load()
+}
+
+// Panics counts the tasks that panicked
+func (p *Pool) Panics() uint64 {
+	return p.panics.Load()
+}
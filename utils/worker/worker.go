@@ -1,16 +1,12 @@
 package worker
 
 import (
+	"context"
 	"math"
-	"runtime/debug"
-	"time"
-
-	"github.com/panjf2000/ants/v2"
-	"github.com/skirrund/gcloud/logger"
 )
 
 type worker struct {
-	p     *ants.Pool
+	p     *Pool
 	Limit int
 }
 
@@ -21,7 +17,7 @@ const (
 )
 
 func init() {
-	p, err := ants.NewPool(DefaultLimit, ants.WithExpiryDuration(10*time.Second))
+	p, err := NewPool("default", Options{Size: DefaultLimit})
 	if err != nil {
 		panic(err)
 	}
@@ -32,7 +28,7 @@ func init() {
 }
 
 func Init(limit int) worker {
-	p, _ := ants.NewPool(limit, ants.WithExpiryDuration(10*time.Second))
+	p, _ := NewPool("", Options{Size: limit})
 	return worker{
 		p:     p,
 		Limit: limit,
@@ -42,15 +38,39 @@ func (w worker) Release() {
 	w.p.Release()
 }
 
+// Pool returns the pool behind the worker
+func (w worker) Pool() *Pool {
+	return w.p
+}
+
+// Running is the number of goroutines running tasks
+func (w worker) Running() int {
+	return w.p.Running()
+}
+
+func (w worker) Cap() int {
+	return w.p.Cap()
+}
+
+// Waiting is the number of tasks waiting for a free goroutine
+func (w worker) Waiting() int {
+	return w.p.Waiting()
+}
+
 func (w worker) Execute(f func()) error {
-	return w.p.Submit(f)
+	return w.p.Go(f)
+}
+
+// ExecuteContext runs f with ctx and its trace id, see Pool.Submit
+func (w worker) ExecuteContext(ctx context.Context, f func(ctx context.Context)) error {
+	return w.p.Submit(ctx, f)
 }
 
 func AsyncExecute(f func()) error {
-	defer func() {
-		if err := recover(); err != nil {
-			logger.Error("[worker] error recover :", err, "\n", string(debug.Stack()))
-		}
-	}()
-	return DefaultWorker.p.Submit(f)
+	return DefaultWorker.Execute(f)
+}
+
+// AsyncExecuteContext runs f on the default pool with ctx and its trace id
+func AsyncExecuteContext(ctx context.Context, f func(ctx context.Context)) error {
+	return DefaultWorker.ExecuteContext(ctx, f)
 }
@@ -1,8 +1,12 @@
 package worker
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/skirrund/gcloud/tracer"
 )
 
 func TestAsyncExecute(t *testing.T) {
@@ -16,3 +20,75 @@ func TestAsyncExecute(t *testing.T) {
 	}
 	println("done")
 }
+
+func TestPool(t *testing.T) {
+	p, err := NewPool("test", Options{Size: 1, QueueSize: 1, Policy: Drop})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		poolsMu.Lock()
+		delete(pools, "test")
+		poolsMu.Unlock()
+	})
+	release := make(chan struct{})
+	started := make(chan struct{})
+	p.Go(func() {
+		close(started)
+		<-release
+	})
+	<-started
+	if err := p.Go(func() {}); err != nil {
+		t.Fatal("queued task rejected:", err)
+	}
+	if err := p.Go(func() {}); err != ErrRejected {
+		t.Fatalf("full queue returned %v", err)
+	}
+
+	ran := false
+	p.Submit(context.Background(), func(ctx context.Context) {
+		ran = true
+	})
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if p.Rejected() != 2 || ran {
+		t.Fatalf("rejected %d, rejected task ran %v", p.Rejected(), ran)
+	}
+	if err := p.Go(func() {}); err != ErrClosed {
+		t.Fatalf("drained pool returned %v", err)
+	}
+}
+
+func TestPoolCallerRunsAndPanics(t *testing.T) {
+	p, err := NewPool("", Options{Size: 1, QueueSize: 1, Policy: CallerRuns})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	p.Go(func() {
+		close(started)
+		<-release
+	})
+	<-started
+	p.Go(func() { panic("queued") })
+	var got any
+	p.Submit(tracer.NewContextFromTraceId("t2"), func(ctx context.Context) {
+		got = tracer.GetTraceID(ctx)
+	})
+	if got != "t2" {
+		t.Fatalf("caller-runs task saw trace id %v", got)
+	}
+	close(release)
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if p.Panics() != 1 {
+		t.Fatalf("panics %d", p.Panics())
+	}
+}